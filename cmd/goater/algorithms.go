@@ -32,3 +32,12 @@ func MapE[T any, R any](slice []T, op func(T) (R, error)) ([]R, error) {
 	}
 	return result, nil
 }
+
+func Contains[T comparable](slice []T, value T) bool {
+	for _, v := range slice {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -94,3 +94,29 @@ func TestReversed(t *testing.T) {
 		})
 	}
 }
+
+func TestContains(t *testing.T) {
+	type args struct {
+		slice []int
+		value int
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{"nil slice", args{nil, 1}, false},
+		{"empty slice", args{make([]int, 0), 1}, false},
+		{"single item, found", args{[]int{1}, 1}, true},
+		{"single item, not found", args{[]int{1}, 2}, false},
+		{"multiple items, found", args{[]int{1, 2, 3}, 3}, true},
+		{"multiple items, not found", args{[]int{1, 2, 3}, 4}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Contains(tt.args.slice, tt.args.value); got != tt.want {
+				t.Errorf("Contains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
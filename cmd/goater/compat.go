@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/tmr232/goat"
+	"io"
+	"os"
+)
+
+// compat runs `goater compat OLD NEW`, printing the changes between two specs written by goat's WriteSpec.
+//
+// It returns the exit code: 1 if there are breaking changes, and 2 if the specs cannot be read.
+func compat(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 2 {
+		fmt.Fprintln(stderr, "usage: goater compat OLD.json NEW.json")
+		return 2
+	}
+	oldSpec, err := readSpecFile(args[0])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	newSpec, err := readSpecFile(args[1])
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	changes := goat.CompareSpecs(oldSpec, newSpec)
+	for _, change := range changes {
+		fmt.Fprintln(stdout, change)
+	}
+	if breaking := goat.BreakingChanges(changes); len(breaking) > 0 {
+		fmt.Fprintf(stdout, "%d breaking changes\n", len(breaking))
+		return 1
+	}
+	return 0
+}
+
+func readSpecFile(path string) (goat.Spec, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return goat.Spec{}, errors.Wrap(err, "failed to read spec")
+	}
+	defer file.Close()
+	spec, err := goat.ReadSpec(file)
+	if err != nil {
+		return goat.Spec{}, errors.Wrap(err, path)
+	}
+	return spec, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompat(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	withFlag := write("with-flag.json", `{"specVersion": 1, "name": "tool", "flags": [{"name": "port", "type": "int", "takesValue": true, "required": false}], "commands": []}`)
+	withoutFlag := write("without-flag.json", `{"specVersion": 1, "name": "tool", "flags": [], "commands": []}`)
+	oldVersion := write("old-version.json", `{"specVersion": 0, "name": "tool"}`)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"unchanged", []string{withFlag, withFlag}, 0},
+		{"added flag", []string{withoutFlag, withFlag}, 0},
+		{"removed flag", []string{withFlag, withoutFlag}, 1},
+		{"missing file", []string{withFlag, filepath.Join(dir, "missing.json")}, 2},
+		{"unsupported version", []string{oldVersion, withFlag}, 2},
+		{"missing args", []string{withFlag}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compat(tt.args, &bytes.Buffer{}, &bytes.Buffer{}); got != tt.want {
+				t.Errorf("compat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"bytes"
+	"fmt"
 	"github.com/pkg/errors"
 	"go/ast"
 	"go/format"
 	"go/token"
+	"strings"
 )
 
 type FluentCall struct {
@@ -62,8 +64,11 @@ func parseFluentChain(call *ast.CallExpr) (FluentChain, bool) {
 }
 
 type ActionDescription struct {
-	Name  *string
-	Usage *string
+	Name       *string
+	Usage      *string
+	Hidden     bool
+	Deprecated *string
+	Category   *string
 }
 
 func isActionDescription(chain FluentChain) bool {
@@ -80,6 +85,22 @@ func isActionDescription(chain FluentChain) bool {
 	return true
 }
 
+// formatSingleArg formats the single argument passed to a directive.
+//
+// The directive's signature (".Name(name)", for example) is used for error reporting.
+func formatSingleArg(fset *token.FileSet, call FluentCall, directive string, reportError func(ast.Node, string)) (string, error) {
+	if len(call.Args) != 1 {
+		reportError(call.Ident, "Expected a single argument for "+directive)
+		return "", errors.New("Wrong number of arguments")
+	}
+	arg, err := formatNode(fset, call.Args[0])
+	if err != nil {
+		reportError(call.Args[0], "Failed handling argument to "+directive)
+		return "", errors.Wrap(err, "Failed formatting argument")
+	}
+	return arg, nil
+}
+
 func parseActionDescription(fset *token.FileSet, chain FluentChain, reportError func(ast.Node, string)) (ActionDescription, error) {
 	description := ActionDescription{}
 
@@ -90,13 +111,9 @@ func parseActionDescription(fset *token.FileSet, chain FluentChain, reportError
 				reportError(call.Ident, "duplicate directive: .Name(name)")
 				return ActionDescription{}, errors.New("Duplicate Name directive found")
 			}
-			if len(call.Args) != 1 {
-				reportError(call.Ident, "Too many arguments passed to .Name(name)")
-			}
-			name, err := formatNode(fset, call.Args[0])
+			name, err := formatSingleArg(fset, call, ".Name(name)", reportError)
 			if err != nil {
-				reportError(call.Args[0], "Failed handling argument to .Name(name)")
-				return ActionDescription{}, errors.Wrap(err, "Failed formatting argument")
+				return ActionDescription{}, err
 			}
 			description.Name = &name
 
@@ -105,16 +122,45 @@ func parseActionDescription(fset *token.FileSet, chain FluentChain, reportError
 				reportError(call.Ident, "duplicate directive: .Usage(usage)")
 				return ActionDescription{}, errors.New("Duplicate Usage directive found")
 			}
-			if len(call.Args) != 1 {
-				reportError(call.Ident, "Too many arguments passed to .Usage(usage)")
-			}
-			usage, err := formatNode(fset, call.Args[0])
+			usage, err := formatSingleArg(fset, call, ".Usage(usage)", reportError)
 			if err != nil {
-				reportError(call.Args[0], "Failed handling argument to .Usage(usage)")
-				return ActionDescription{}, errors.Wrap(err, "Failed formatting argument")
+				return ActionDescription{}, err
 			}
 			description.Usage = &usage
 
+		case "Category":
+			if description.Category != nil {
+				reportError(call.Ident, "duplicate directive: .Category(category)")
+				return ActionDescription{}, errors.New("Duplicate Category directive found")
+			}
+			category, err := formatSingleArg(fset, call, ".Category(category)", reportError)
+			if err != nil {
+				return ActionDescription{}, err
+			}
+			description.Category = &category
+
+		case "Hidden":
+			if description.Hidden {
+				reportError(call.Ident, "duplicate directive: .Hidden()")
+				return ActionDescription{}, errors.New("Duplicate Hidden directive found")
+			}
+			if len(call.Args) != 0 {
+				reportError(call.Ident, "Expected no arguments for .Hidden()")
+				return ActionDescription{}, errors.New("Wrong number of arguments")
+			}
+			description.Hidden = true
+
+		case "Deprecated":
+			if description.Deprecated != nil {
+				reportError(call.Ident, "duplicate directive: .Deprecated(message)")
+				return ActionDescription{}, errors.New("Duplicate Deprecated directive found")
+			}
+			message, err := formatSingleArg(fset, call, ".Deprecated(message)", reportError)
+			if err != nil {
+				return ActionDescription{}, err
+			}
+			description.Deprecated = &message
+
 		default:
 			reportError(call.Ident, "Unrecognized directive: "+call.Name)
 			return ActionDescription{}, errors.New("unrecognized directive")
@@ -125,11 +171,39 @@ func parseActionDescription(fset *token.FileSet, chain FluentChain, reportError
 }
 
 type FlagDescription struct {
-	Id      string
-	Type    string
-	Name    *string
-	Usage   *string
-	Default *string
+	Id         string
+	Type       string
+	Name       *string
+	Usage      *string
+	Default    *string
+	Layout     *string
+	Separator  *string
+	Count      bool
+	File       bool
+	Hidden     bool
+	Deprecated *string
+	Category   *string
+	// Complete is the function passed to .Complete(fn), if any.
+	Complete *string
+	// Validators are the flags.Option expressions of the validation directives.
+	Validators []string
+	// EnvVars are the expressions passed to .EnvVar(envVars...).
+	EnvVars []string
+	// AliasExprs are the expressions passed to .Alias(...) and .Short(short).
+	AliasExprs []ast.Expr
+	// hasShort is used for detecting duplicate .Short(short) directives.
+	hasShort bool
+	// NameExpr is the expression passed to .Name(name), if any.
+	NameExpr ast.Expr
+	// ConstName and Aliases are the values of NameExpr and AliasExprs, filled in by the Goatherd.
+	ConstName *FlagName
+	Aliases   []FlagName
+}
+
+// FlagName is a name or an alias of a flag, along with the position it is declared at.
+type FlagName struct {
+	Name string
+	Pos  token.Pos
 }
 
 func isFlagDescription(chain FluentChain) bool {
@@ -146,6 +220,14 @@ func isFlagDescription(chain FluentChain) bool {
 	return true
 }
 
+func isTimeType(typ string) bool {
+	return typ == "time.Time" || typ == "*time.Time"
+}
+
+func isRepeatedType(typ string) bool {
+	return strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[")
+}
+
 func parseFlagDescription(fset *token.FileSet, chain FluentChain, getType func(expr ast.Expr) (string, error), reportError func(ast.Node, string)) (FlagDescription, error) {
 	id, err := formatNode(fset, chain.Calls[0].Args[0])
 	if err != nil {
@@ -165,45 +247,179 @@ func parseFlagDescription(fset *token.FileSet, chain FluentChain, getType func(e
 				reportError(call.Ident, "duplicate directive: .Name(name)")
 				return FlagDescription{}, errors.New("Duplicate Name directive found")
 			}
-			if len(call.Args) != 1 {
-				reportError(call.Ident, "Too many arguments passed to .Name(name)")
-			}
-			name, err := formatNode(fset, call.Args[0])
+			name, err := formatSingleArg(fset, call, ".Name(name)", reportError)
 			if err != nil {
-				reportError(call.Args[0], "Failed handling argument to .Name(name)")
-				return FlagDescription{}, errors.Wrap(err, "Failed formatting argument")
+				return FlagDescription{}, err
 			}
 			description.Name = &name
+			description.NameExpr = call.Args[0]
 
 		case "Usage":
 			if description.Usage != nil {
 				reportError(call.Ident, "duplicate directive: .Usage(usage)")
 				return FlagDescription{}, errors.New("Duplicate Usage directive found")
 			}
-			if len(call.Args) != 1 {
-				reportError(call.Ident, "Too many arguments passed to .Usage(usage)")
-			}
-			usage, err := formatNode(fset, call.Args[0])
+			usage, err := formatSingleArg(fset, call, ".Usage(usage)", reportError)
 			if err != nil {
-				reportError(call.Args[0], "Failed handling argument to .Usage(usage)")
-				return FlagDescription{}, errors.Wrap(err, "Failed formatting argument")
+				return FlagDescription{}, err
 			}
 			description.Usage = &usage
 
+		case "Alias":
+			if len(call.Args) == 0 {
+				reportError(call.Ident, "Expected at least one argument for .Alias(aliases...)")
+				return FlagDescription{}, errors.New("Wrong number of arguments")
+			}
+			description.AliasExprs = append(description.AliasExprs, call.Args...)
+
+		case "EnvVar":
+			if len(call.Args) == 0 {
+				reportError(call.Ident, "Expected at least one argument for .EnvVar(envVars...)")
+				return FlagDescription{}, errors.New("Wrong number of arguments")
+			}
+			for _, arg := range call.Args {
+				envVar, err := formatNode(fset, arg)
+				if err != nil {
+					reportError(arg, "Failed handling argument to .EnvVar(envVars...)")
+					return FlagDescription{}, errors.Wrap(err, "Failed formatting argument")
+				}
+				description.EnvVars = append(description.EnvVars, envVar)
+			}
+
+		case "Short":
+			if description.hasShort {
+				reportError(call.Ident, "duplicate directive: .Short(short)")
+				return FlagDescription{}, errors.New("Duplicate Short directive found")
+			}
+			if len(call.Args) != 1 {
+				reportError(call.Ident, "Expected a single argument for .Short(short)")
+				return FlagDescription{}, errors.New("Wrong number of arguments")
+			}
+			description.hasShort = true
+			description.AliasExprs = append(description.AliasExprs, call.Args[0])
+
 		case "Default":
 			if description.Default != nil {
 				reportError(call.Ident, "duplicate directive: .Default(default_)")
 				return FlagDescription{}, errors.New("Duplicate Default directive found")
 			}
-			if len(call.Args) != 1 {
-				reportError(call.Ident, "Too many arguments passed to .Default(default_)")
-			}
-			default_, err := formatNode(fset, call.Args[0])
+			default_, err := formatSingleArg(fset, call, ".Default(default_)", reportError)
 			if err != nil {
-				reportError(call.Args[0], "Failed handling argument to .Default(default_)")
-				return FlagDescription{}, errors.Wrap(err, "Failed formatting argument")
+				return FlagDescription{}, err
 			}
 			description.Default = &default_
+
+		case "Layout":
+			if description.Layout != nil {
+				reportError(call.Ident, "duplicate directive: .Layout(layout)")
+				return FlagDescription{}, errors.New("Duplicate Layout directive found")
+			}
+			if !isTimeType(typ) {
+				reportError(call.Ident, ".Layout(layout) can only be used with time.Time flags")
+				return FlagDescription{}, errors.New("Layout directive used on a non-time flag")
+			}
+			layout, err := formatSingleArg(fset, call, ".Layout(layout)", reportError)
+			if err != nil {
+				return FlagDescription{}, err
+			}
+			description.Layout = &layout
+
+		case "Separator":
+			if description.Separator != nil {
+				reportError(call.Ident, "duplicate directive: .Separator(separator)")
+				return FlagDescription{}, errors.New("Duplicate Separator directive found")
+			}
+			if !isRepeatedType(typ) {
+				reportError(call.Ident, ".Separator(separator) can only be used with slice and map flags")
+				return FlagDescription{}, errors.New("Separator directive used on a non-repeated flag")
+			}
+			separator, err := formatSingleArg(fset, call, ".Separator(separator)", reportError)
+			if err != nil {
+				return FlagDescription{}, err
+			}
+			description.Separator = &separator
+
+		case "Category":
+			if description.Category != nil {
+				reportError(call.Ident, "duplicate directive: .Category(category)")
+				return FlagDescription{}, errors.New("Duplicate Category directive found")
+			}
+			category, err := formatSingleArg(fset, call, ".Category(category)", reportError)
+			if err != nil {
+				return FlagDescription{}, err
+			}
+			description.Category = &category
+
+		case "Hidden":
+			if description.Hidden {
+				reportError(call.Ident, "duplicate directive: .Hidden()")
+				return FlagDescription{}, errors.New("Duplicate Hidden directive found")
+			}
+			if len(call.Args) != 0 {
+				reportError(call.Ident, "Expected no arguments for .Hidden()")
+				return FlagDescription{}, errors.New("Wrong number of arguments")
+			}
+			description.Hidden = true
+
+		case "Deprecated":
+			if description.Deprecated != nil {
+				reportError(call.Ident, "duplicate directive: .Deprecated(message)")
+				return FlagDescription{}, errors.New("Duplicate Deprecated directive found")
+			}
+			message, err := formatSingleArg(fset, call, ".Deprecated(message)", reportError)
+			if err != nil {
+				return FlagDescription{}, err
+			}
+			description.Deprecated = &message
+
+		case "Range", "MinLen", "Pattern", "OneOf", "Validate":
+			validator, err := formatValidator(fset, call, strings.TrimPrefix(typ, "*"), reportError)
+			if err != nil {
+				return FlagDescription{}, err
+			}
+			description.Validators = append(description.Validators, validator)
+
+		case "Count":
+			if description.Count {
+				reportError(call.Ident, "duplicate directive: .Count()")
+				return FlagDescription{}, errors.New("Duplicate Count directive found")
+			}
+			if typ != "int" {
+				reportError(call.Ident, ".Count() can only be used with int flags")
+				return FlagDescription{}, errors.New("Count directive used on a non-int flag")
+			}
+			if len(call.Args) != 0 {
+				reportError(call.Ident, "Expected no arguments for .Count()")
+				return FlagDescription{}, errors.New("Wrong number of arguments")
+			}
+			description.Count = true
+
+		case "File":
+			if description.File {
+				reportError(call.Ident, "duplicate directive: .File()")
+				return FlagDescription{}, errors.New("Duplicate File directive found")
+			}
+			if typ != "string" && typ != "*string" && typ != "[]string" {
+				reportError(call.Ident, ".File() can only be used with string flags")
+				return FlagDescription{}, errors.New("File directive used on a non-string flag")
+			}
+			if len(call.Args) != 0 {
+				reportError(call.Ident, "Expected no arguments for .File()")
+				return FlagDescription{}, errors.New("Wrong number of arguments")
+			}
+			description.File = true
+
+		case "Complete":
+			if description.Complete != nil {
+				reportError(call.Ident, "duplicate directive: .Complete(fn)")
+				return FlagDescription{}, errors.New("Duplicate Complete directive found")
+			}
+			complete, err := formatSingleArg(fset, call, ".Complete(fn)", reportError)
+			if err != nil {
+				return FlagDescription{}, err
+			}
+			description.Complete = &complete
+
 		default:
 			reportError(call.Ident, "Unrecognized directive: "+call.Name)
 			return FlagDescription{}, errors.New("unrecognized directive")
@@ -212,3 +428,169 @@ func parseFlagDescription(fset *token.FileSet, chain FluentChain, getType func(e
 
 	return description, nil
 }
+
+// validatorSignatures are the signatures of the validation directives, used for error reporting.
+var validatorSignatures = map[string]string{
+	"Range":    ".Range(min, max)",
+	"MinLen":   ".MinLen(minLen)",
+	"Pattern":  ".Pattern(pattern)",
+	"OneOf":    ".OneOf(values...)",
+	"Validate": ".Validate(fn)",
+}
+
+// formatValidator formats a validation directive as the matching flags.Option.
+//
+// The generic validators are instantiated with the type of the flag (or the type it points to),
+// so that the compiler checks their arguments as well.
+func formatValidator(fset *token.FileSet, call FluentCall, valueType string, reportError func(ast.Node, string)) (string, error) {
+	signature := validatorSignatures[call.Name]
+	var expectedArgs int
+	switch call.Name {
+	case "Range":
+		expectedArgs = 2
+	case "OneOf":
+		if len(call.Args) == 0 {
+			reportError(call.Ident, "Expected at least one argument for "+signature)
+			return "", errors.New("Wrong number of arguments")
+		}
+		expectedArgs = len(call.Args)
+	default:
+		expectedArgs = 1
+	}
+	if len(call.Args) != expectedArgs {
+		if expectedArgs == 1 {
+			reportError(call.Ident, "Expected a single argument for "+signature)
+		} else {
+			reportError(call.Ident, fmt.Sprintf("Expected %d arguments for %s", expectedArgs, signature))
+		}
+		return "", errors.New("Wrong number of arguments")
+	}
+	args := make([]string, len(call.Args))
+	for i, arg := range call.Args {
+		formatted, err := formatNode(fset, arg)
+		if err != nil {
+			reportError(arg, "Failed handling argument to "+signature)
+			return "", errors.Wrap(err, "Failed formatting argument")
+		}
+		args[i] = formatted
+	}
+	typeArgs := ""
+	if call.Name == "Range" || call.Name == "OneOf" {
+		typeArgs = "[" + valueType + "]"
+	}
+	return "flags." + call.Name + typeArgs + "(" + strings.Join(args, ", ") + ")", nil
+}
+
+type ArgDescription struct {
+	Id      string
+	Type    string
+	Name    *string
+	Usage   *string
+	Default *string
+}
+
+func isArgDescription(chain FluentChain) bool {
+	base, isIdent := chain.Base.(*ast.Ident)
+	if !isIdent {
+		return false
+	}
+	if base.Name != "goat" {
+		return false
+	}
+	if chain.Calls[0].Name != "Arg" {
+		return false
+	}
+	return true
+}
+
+func parseArgDescription(fset *token.FileSet, chain FluentChain, getType func(expr ast.Expr) (string, error), reportError func(ast.Node, string)) (ArgDescription, error) {
+	id, err := formatNode(fset, chain.Calls[0].Args[0])
+	if err != nil {
+		return ArgDescription{}, errors.Wrap(err, "Could not format id node")
+	}
+	typ, err := getType(chain.Calls[0].Args[0])
+	if err != nil {
+		return ArgDescription{}, errors.Wrap(err, "Failed getting id type")
+	}
+
+	description := ArgDescription{Id: id, Type: typ}
+
+	for _, call := range chain.Calls[1:] {
+		switch call.Name {
+		case "Name":
+			if description.Name != nil {
+				reportError(call.Ident, "duplicate directive: .Name(name)")
+				return ArgDescription{}, errors.New("Duplicate Name directive found")
+			}
+			name, err := formatSingleArg(fset, call, ".Name(name)", reportError)
+			if err != nil {
+				return ArgDescription{}, err
+			}
+			description.Name = &name
+
+		case "Usage":
+			if description.Usage != nil {
+				reportError(call.Ident, "duplicate directive: .Usage(usage)")
+				return ArgDescription{}, errors.New("Duplicate Usage directive found")
+			}
+			usage, err := formatSingleArg(fset, call, ".Usage(usage)", reportError)
+			if err != nil {
+				return ArgDescription{}, err
+			}
+			description.Usage = &usage
+
+		case "Default":
+			if description.Default != nil {
+				reportError(call.Ident, "duplicate directive: .Default(default_)")
+				return ArgDescription{}, errors.New("Duplicate Default directive found")
+			}
+			default_, err := formatSingleArg(fset, call, ".Default(default_)", reportError)
+			if err != nil {
+				return ArgDescription{}, err
+			}
+			description.Default = &default_
+
+		default:
+			reportError(call.Ident, "Unrecognized directive: "+call.Name)
+			return ArgDescription{}, errors.New("unrecognized directive")
+		}
+	}
+
+	return description, nil
+}
+
+// FlagConstraint is a constraint on a group of flags, like `goat.Exclusive(a, b)`.
+type FlagConstraint struct {
+	// Kind is the name of the goat function, either "Exclusive" or "Together".
+	Kind string
+	Ids  []*ast.Ident
+}
+
+func isFlagConstraint(chain FluentChain) bool {
+	base, isIdent := chain.Base.(*ast.Ident)
+	if !isIdent {
+		return false
+	}
+	if base.Name != "goat" || len(chain.Calls) != 1 {
+		return false
+	}
+	return chain.Calls[0].Name == "Exclusive" || chain.Calls[0].Name == "Together"
+}
+
+func parseFlagConstraint(chain FluentChain, reportError func(ast.Node, string)) (FlagConstraint, error) {
+	call := chain.Calls[0]
+	if len(call.Args) < 2 {
+		reportError(call.Ident, "Expected at least two flags for goat."+call.Name)
+		return FlagConstraint{}, errors.New("Wrong number of arguments")
+	}
+	constraint := FlagConstraint{Kind: call.Name}
+	for _, arg := range call.Args {
+		id, isIdent := arg.(*ast.Ident)
+		if !isIdent {
+			reportError(arg, "Expected a parameter of the function in goat."+call.Name)
+			return FlagConstraint{}, errors.New("Constraint argument is not an identifier")
+		}
+		constraint.Ids = append(constraint.Ids, id)
+	}
+	return constraint, nil
+}
@@ -5,14 +5,21 @@ import (
 	_ "embed"
 	"fmt"
 	"github.com/pkg/errors"
-	"github.com/tmr232/goat"
+	"github.com/tmr232/goat/flags"
 	"go/ast"
+	"go/constant"
 	"go/format"
+	"go/token"
 	"go/types"
 	"golang.org/x/tools/go/packages"
+	"io"
 	"log"
 	"os"
+	"path"
 	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 )
@@ -46,9 +53,32 @@ func loadPackages() *packages.Package {
 //go:embed goat.tmpl
 var coreTemplate string
 
+//go:embed stdflag.tmpl
+var stdflagTemplate string
+
 type Goatherd struct {
 	pkg      *packages.Package
 	template *template.Template
+	// backend is the runtime the code is generated for, which is cliBackend unless set otherwise.
+	backend string
+	// extraImports holds the imports needed for types and expressions copied into the generated code.
+	extraImports map[importSpec]bool
+	// registeredTypes holds the types registered using flags.RegisterTypeHandler by the package and its dependencies.
+	registeredTypes []types.Type
+	// output is where errors are reported, and is os.Stdout unless set otherwise.
+	output io.Writer
+}
+
+type importSpec struct {
+	Name string
+	Path string
+}
+
+func (spec importSpec) String() string {
+	if spec.Name == path.Base(spec.Path) {
+		return strconv.Quote(spec.Path)
+	}
+	return spec.Name + " " + strconv.Quote(spec.Path)
 }
 
 func NewGoatherd(pkg *packages.Package) *Goatherd {
@@ -60,7 +90,81 @@ func NewGoatherd(pkg *packages.Package) *Goatherd {
 	if err != nil {
 		log.Fatal(err)
 	}
-	return &Goatherd{pkg: pkg, template: t}
+	if _, err := t.New("stdflag").Parse(stdflagTemplate); err != nil {
+		log.Fatal(err)
+	}
+	gh := &Goatherd{pkg: pkg, template: t, backend: cliBackend, extraImports: make(map[importSpec]bool), output: os.Stdout}
+	gh.findRegisteredTypes(pkg, make(map[string]bool))
+	return gh
+}
+
+// flagsPath is the import path of the flags package.
+var flagsPath = reflect.TypeOf(flags.Description{}).PkgPath()
+
+// findRegisteredTypes finds the types registered using flags.RegisterTypeHandler by a package and its dependencies.
+//
+// The handlers are registered when the generated code runs, so flags.IsRegistered only knows the
+// types of the flags package itself.
+func (gh *Goatherd) findRegisteredTypes(pkg *packages.Package, visited map[string]bool) {
+	if visited[pkg.PkgPath] {
+		return
+	}
+	visited[pkg.PkgPath] = true
+	for _, imported := range pkg.Imports {
+		gh.findRegisteredTypes(imported, visited)
+	}
+	if _, importsFlags := pkg.Imports[flagsPath]; !importsFlags || pkg.TypesInfo == nil {
+		return
+	}
+	for ident, instance := range pkg.TypesInfo.Instances {
+		f, isFunc := pkg.TypesInfo.Uses[ident].(*types.Func)
+		if isFunc && f.Pkg() != nil && f.Pkg().Path() == flagsPath && f.Name() == "RegisterTypeHandler" && instance.TypeArgs.Len() == 1 {
+			gh.registeredTypes = append(gh.registeredTypes, instance.TypeArgs.At(0))
+		}
+	}
+}
+
+// isRegistered reports whether a type has a type handler, either in the flags package,
+// or registered by the package or its dependencies.
+func (gh *Goatherd) isRegistered(typ types.Type) bool {
+	if flags.IsRegistered(gh.typeString(typ)) {
+		return true
+	}
+	for _, registered := range gh.registeredTypes {
+		if types.Identical(typ, registered) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeString formats a type for use in the generated code.
+//
+// Types from the current package are unqualified, and types from other packages
+// are qualified by their package name and recorded as imports.
+func (gh *Goatherd) typeString(typ types.Type) string {
+	return types.TypeString(typ, func(pkg *types.Package) string {
+		if pkg.Path() == gh.pkg.PkgPath {
+			return ""
+		}
+		gh.extraImports[importSpec{Name: pkg.Name(), Path: pkg.Path()}] = true
+		return pkg.Name()
+	})
+}
+
+// recordExprImports records the imports used by an expression that is copied into the generated code.
+func (gh *Goatherd) recordExprImports(expr ast.Expr) {
+	ast.Inspect(expr, func(node ast.Node) bool {
+		ident, isIdent := node.(*ast.Ident)
+		if !isIdent {
+			return true
+		}
+		pkgName, isPkgName := gh.pkg.TypesInfo.Uses[ident].(*types.PkgName)
+		if isPkgName {
+			gh.extraImports[importSpec{Name: ident.Name, Path: pkgName.Imported().Path()}] = true
+		}
+		return true
+	})
 }
 
 func (gh *Goatherd) Render(name string, data any) (string, error) {
@@ -77,14 +181,14 @@ func (gh *Goatherd) isGoatRun(node *ast.CallExpr) bool {
 	if len(node.Args) != 1 {
 		return false
 	}
-	return gh.isCallTo(node, "github.com/tmr232/goat", "RunE") ||
-		gh.isCallTo(node, "github.com/tmr232/goat", "Run")
+	return gh.isCallTo(node, runtimePaths[gh.backend], "RunE") ||
+		gh.isCallTo(node, runtimePaths[gh.backend], "Run")
 }
 func (gh *Goatherd) isGoatCommand(node *ast.CallExpr) bool {
 	if len(node.Args) < 1 {
 		return false
 	}
-	return gh.isCallTo(node, "github.com/tmr232/goat", "Command")
+	return gh.isCallTo(node, runtimePaths[gh.backend], "Command")
 }
 
 type callTarget struct {
@@ -209,8 +313,15 @@ func findActionCalls(gh *Goatherd) []ast.Expr {
 }
 
 type GoatArg struct {
-	Name string
-	Type string
+	Name      string
+	Type      string
+	IsContext bool
+	// Options holds the flags.Option expressions implied by the argument's type.
+	Options []string
+	// IsVariadic is true for a trailing variadic parameter, in which case Type is the type of its elements.
+	IsVariadic bool
+	// Fields holds the flags of a struct parameter, which is a group of flags.
+	Fields []GoatField
 }
 type GoatSignature struct {
 	Name    string
@@ -240,16 +351,151 @@ func (gh *Goatherd) parseSignature(f *types.Func) (signature GoatSignature, err
 	for i := 0; i < funcSignature.Params().Len(); i++ {
 		param := funcSignature.Params().At(i)
 		paramName := param.Name()
-		paramType := param.Type().String()
-		signature.Args = append(signature.Args, GoatArg{Name: paramName, Type: paramType})
+		typ := param.Type()
+		isVariadic := funcSignature.Variadic() && i == funcSignature.Params().Len()-1
+		if isVariadic {
+			// Variadic parameters take the remaining positional arguments, converting each of them.
+			typ = typ.(*types.Slice).Elem()
+		}
+		paramType := gh.typeString(typ)
+		arg := GoatArg{
+			Name:       paramName,
+			Type:       paramType,
+			IsContext:  gh.isGoatContext(typ),
+			Options:    gh.enumChoices(typ),
+			IsVariadic: isVariadic,
+		}
+		if contextBackend, isContext := contextBackend(typ); isContext && contextBackend != gh.backend {
+			gh.reportErrorAt(param.Pos(), fmt.Sprintf("%s is a context of the %s backend, but the code is generated for the %s backend", paramName, contextBackend, gh.backend))
+			err = errors.New("Context of another backend")
+		} else if !arg.IsContext && !isVariadic && gh.isFlagGroup(typ) {
+			var groupErr error
+			arg.Fields, groupErr = gh.flagGroup(param)
+			if groupErr != nil {
+				err = groupErr
+			} else if len(arg.Fields) == 0 {
+				gh.reportErrorAt(param.Pos(), fmt.Sprintf("%s has no exported fields to use as flags", paramType))
+				err = errors.New("Empty flag group")
+			}
+		} else if !arg.IsContext && !gh.isSupportedType(typ, arg.Options) {
+			gh.reportErrorAt(param.Pos(), fmt.Sprintf("Unsupported flag type %s for %s: no type handler, and it does not implement encoding.TextUnmarshaler or flag.Value", paramType, paramName))
+			err = errors.New("Unsupported flag type")
+		}
+		signature.Args = append(signature.Args, arg)
+	}
+	if err != nil {
+		return GoatSignature{}, err
 	}
 	return signature, nil
 }
 
+// newInterface creates an interface type with a single method.
+func newInterface(methodName string, params, results []types.Type) *types.Interface {
+	toTuple := func(typs []types.Type) *types.Tuple {
+		return types.NewTuple(Map(typs, func(typ types.Type) *types.Var {
+			return types.NewParam(token.NoPos, nil, "", typ)
+		})...)
+	}
+	signature := types.NewSignatureType(nil, nil, nil, toTuple(params), toTuple(results), false)
+	method := types.NewFunc(token.NoPos, nil, methodName, signature)
+	return types.NewInterfaceType([]*types.Func{method}, nil).Complete()
+}
+
+var errorType = types.Universe.Lookup("error").Type()
+
+// textUnmarshalerInterface and the flagValueInterfaces mirror encoding.TextUnmarshaler and flag.Value.
+var textUnmarshalerInterface = newInterface("UnmarshalText", []types.Type{types.NewSlice(types.Typ[types.Byte])}, []types.Type{errorType})
+var flagValueInterfaces = []*types.Interface{
+	newInterface("Set", []types.Type{types.Typ[types.String]}, []types.Type{errorType}),
+	newInterface("String", nil, []types.Type{types.Typ[types.String]}),
+}
+
+func implementsTextInterfaces(typ types.Type) bool {
+	if types.Implements(typ, textUnmarshalerInterface) {
+		return true
+	}
+	for _, iface := range flagValueInterfaces {
+		if !types.Implements(typ, iface) {
+			return false
+		}
+	}
+	return true
+}
+
+// isTextType reports whether flags of the type can be parsed using the encoding.TextUnmarshaler
+// or flag.Value interfaces, implemented by a pointer to the type.
+// Like other types, pointers to such types result in optional flags.
+func isTextType(typ types.Type) bool {
+	if implementsTextInterfaces(types.NewPointer(typ)) {
+		return true
+	}
+	_, isPointer := typ.(*types.Pointer)
+	return isPointer && implementsTextInterfaces(typ)
+}
+
+// enumChoices returns the flags.Choice options for enum types.
+//
+// Enum types are named string or integer types without a type handler, that have constants
+// declared in their package. String enums are chosen by value, and integer enums by constant name.
+// Pointers to enum types are enums as well.
+func (gh *Goatherd) enumChoices(typ types.Type) []string {
+	if gh.isRegistered(typ) {
+		return nil
+	}
+	if pointer, isPointer := typ.(*types.Pointer); isPointer {
+		typ = pointer.Elem()
+	}
+	named, isNamed := typ.(*types.Named)
+	if !isNamed || named.Obj().Pkg() == nil {
+		return nil
+	}
+	basic, isBasic := named.Underlying().(*types.Basic)
+	if !isBasic || basic.Info()&(types.IsString|types.IsInteger) == 0 {
+		return nil
+	}
+
+	pkg := named.Obj().Pkg()
+	var constants []*types.Const
+	for _, name := range pkg.Scope().Names() {
+		constant, isConst := pkg.Scope().Lookup(name).(*types.Const)
+		if !isConst || !types.Identical(constant.Type(), named) {
+			continue
+		}
+		if pkg.Path() != gh.pkg.PkgPath && !constant.Exported() {
+			continue
+		}
+		constants = append(constants, constant)
+	}
+	// Keep the declaration order, as it is usually meaningful.
+	sort.Slice(constants, func(i, j int) bool {
+		return constants[i].Pos() < constants[j].Pos()
+	})
+
+	var choices []string
+	for _, constant := range constants {
+		choiceName := strconv.Quote(constant.Name())
+		if basic.Info()&types.IsString != 0 {
+			choiceName = constant.Val().ExactString()
+		}
+		choiceValue := constant.Name()
+		if pkg.Path() != gh.pkg.PkgPath {
+			gh.extraImports[importSpec{Name: pkg.Name(), Path: pkg.Path()}] = true
+			choiceValue = pkg.Name() + "." + choiceValue
+		}
+		choices = append(choices, fmt.Sprintf("flags.Choice(%s, %s)", choiceName, choiceValue))
+	}
+	return choices
+}
+
 var notAFlagDescription = errors.New("Not a flag description")
+var notAnArgDescription = errors.New("Not an argument description")
 
 func (gh *Goatherd) reportError(node ast.Node, message string) {
-	fmt.Println(gh.pkg.Fset.Position(node.Pos()), "Error:", message)
+	gh.reportErrorAt(node.Pos(), message)
+}
+
+func (gh *Goatherd) reportErrorAt(pos token.Pos, message string) {
+	fmt.Fprintln(gh.output, gh.pkg.Fset.Position(pos), "Error:", message)
 }
 func (gh *Goatherd) parseActionDescription(fdecl *ast.FuncDecl) (ActionDescription, error) {
 	var description ActionDescription
@@ -324,15 +570,536 @@ func (gh *Goatherd) parseFlagDescription(callExpr *ast.CallExpr) (FlagDescriptio
 		if argType == nil {
 			return "", errors.New("Failed to find type of expression.")
 		}
-		return argType.String(), nil
+		return gh.typeString(argType), nil
 	},
 		gh.reportError)
 	if err != nil {
 		return FlagDescription{}, err
 	}
+	for _, call := range chain.Calls[1:] {
+		for _, arg := range call.Args {
+			gh.recordExprImports(arg)
+		}
+		if call.Name == "Default" && len(call.Args) == 1 {
+			err = gh.checkDefaultValue(chain.Calls[0].Args[0], call.Args[0])
+			if err != nil {
+				return FlagDescription{}, err
+			}
+		}
+		if _, isValidator := validatorSignatures[call.Name]; isValidator {
+			if validatorErr := gh.checkValidator(chain.Calls[0].Args[0], call); validatorErr != nil {
+				err = validatorErr
+			}
+		}
+		if call.Name == "Complete" && len(call.Args) == 1 {
+			if completeErr := gh.checkCompleteFunc(call.Args[0]); completeErr != nil {
+				err = completeErr
+			}
+		}
+	}
+	if description.NameExpr != nil {
+		if name, isConstant := gh.constantName(description.NameExpr); isConstant {
+			description.ConstName = &FlagName{Name: name, Pos: description.NameExpr.Pos()}
+		}
+	}
+	for _, aliasExpr := range description.AliasExprs {
+		alias, isConstant := gh.constantName(aliasExpr)
+		if !isConstant {
+			gh.reportError(aliasExpr, "Flag aliases must be constants")
+			err = errors.New("Non-constant alias")
+			continue
+		}
+		description.Aliases = append(description.Aliases, FlagName{Name: alias, Pos: aliasExpr.Pos()})
+	}
+	if err != nil {
+		return FlagDescription{}, err
+	}
 	return description, nil
 }
 
+// constantName gets the value of a constant flag name, which is either a string or a rune.
+func (gh *Goatherd) constantName(expr ast.Expr) (string, bool) {
+	value := gh.pkg.TypesInfo.Types[expr].Value
+	if value == nil {
+		return "", false
+	}
+	switch value.Kind() {
+	case constant.String:
+		return constant.StringVal(value), true
+	case constant.Int:
+		r, isExact := constant.Int64Val(value)
+		return string(rune(r)), isExact
+	}
+	return "", false
+}
+
+func (gh *Goatherd) parseArgDescriptions(fdecl *ast.FuncDecl) ([]ArgDescription, error) {
+	var parseErrors []error
+
+	var descriptions []ArgDescription
+	ast.Inspect(fdecl.Body, func(node ast.Node) bool {
+		callExpr, isCall := node.(*ast.CallExpr)
+		if !isCall {
+			// Keep going!
+			return true
+		}
+		description, err := gh.parseArgDescription(callExpr)
+		if err == notAnArgDescription {
+			// Keep going
+			return true
+		}
+		if err != nil {
+			parseErrors = append(parseErrors, err)
+		}
+		descriptions = append(descriptions, description)
+
+		// Stop this branch
+		return false
+	})
+
+	if len(parseErrors) != 0 {
+		return nil, errors.New("Encountered errors!")
+	}
+	return descriptions, nil
+}
+
+func (gh *Goatherd) parseArgDescription(callExpr *ast.CallExpr) (ArgDescription, error) {
+	chain, isChain := parseFluentChain(callExpr)
+	if !isChain || !isArgDescription(chain) {
+		return ArgDescription{}, notAnArgDescription
+	}
+	description, err := parseArgDescription(gh.pkg.Fset, chain, func(expr ast.Expr) (string, error) {
+		argType := gh.pkg.TypesInfo.TypeOf(expr)
+		if argType == nil {
+			return "", errors.New("Failed to find type of expression.")
+		}
+		return gh.typeString(argType), nil
+	},
+		gh.reportError)
+	if err != nil {
+		return ArgDescription{}, err
+	}
+	for _, call := range chain.Calls[1:] {
+		for _, arg := range call.Args {
+			gh.recordExprImports(arg)
+		}
+		if call.Name == "Default" && len(call.Args) == 1 {
+			err = gh.checkDefaultValue(chain.Calls[0].Args[0], call.Args[0])
+			if err != nil {
+				return ArgDescription{}, err
+			}
+		}
+	}
+	return description, nil
+}
+
+func (gh *Goatherd) parseFlagConstraints(fdecl *ast.FuncDecl) ([]FlagConstraint, error) {
+	var parseErrors []error
+
+	var constraints []FlagConstraint
+	ast.Inspect(fdecl.Body, func(node ast.Node) bool {
+		callExpr, isCall := node.(*ast.CallExpr)
+		if !isCall {
+			// Keep going!
+			return true
+		}
+		chain, isChain := parseFluentChain(callExpr)
+		if !isChain || !isFlagConstraint(chain) {
+			// Keep going
+			return true
+		}
+		constraint, err := parseFlagConstraint(chain, gh.reportError)
+		if err != nil {
+			parseErrors = append(parseErrors, err)
+		}
+		constraints = append(constraints, constraint)
+
+		// Stop this branch
+		return false
+	})
+
+	if len(parseErrors) != 0 {
+		return nil, errors.New("Encountered errors!")
+	}
+	return constraints, nil
+}
+
+// checkFlagConstraints makes sure that flag constraints only refer to flags of the function,
+// and refer to every flag at most once.
+func (gh *Goatherd) checkFlagConstraints(signature GoatSignature, f *types.Func, constraints []FlagConstraint, argDescriptions []ArgDescription) (err error) {
+	isArg := make(map[string]bool)
+	for _, desc := range argDescriptions {
+		isArg[desc.Id] = true
+	}
+	params := f.Type().(*types.Signature).Params()
+	argByParam := make(map[types.Object]GoatArg)
+	for i, arg := range signature.Args {
+		argByParam[params.At(i)] = arg
+	}
+
+	for _, constraint := range constraints {
+		seen := make(map[string]bool)
+		for _, id := range constraint.Ids {
+			arg, isParam := argByParam[gh.pkg.TypesInfo.Uses[id]]
+			switch {
+			case !isParam:
+				gh.reportError(id, fmt.Sprintf("%s is not a parameter of %s", id.Name, f.Name()))
+				err = errors.New("Constraint on a non-parameter")
+			case arg.IsContext || arg.IsVariadic || isArg[arg.Name]:
+				gh.reportError(id, fmt.Sprintf("%s is not a flag, and can't be used in goat.%s", id.Name, constraint.Kind))
+				err = errors.New("Constraint on a non-flag")
+			case len(arg.Fields) != 0:
+				gh.reportError(id, fmt.Sprintf("%s is a group of flags, and can't be used in goat.%s", id.Name, constraint.Kind))
+				err = errors.New("Constraint on a flag group")
+			case seen[id.Name]:
+				gh.reportError(id, fmt.Sprintf("%s is used more than once in goat.%s", id.Name, constraint.Kind))
+				err = errors.New("Duplicate flag in constraint")
+			}
+			seen[id.Name] = true
+		}
+	}
+	return err
+}
+
+// checkArgs makes sure that the positional arguments of a function can be parsed unambiguously.
+//
+// Every parameter can be either a flag or an argument, and optional arguments must come
+// after all the required ones. A variadic parameter is always an argument.
+func (gh *Goatherd) checkArgs(f *types.Func, flagDescriptions []FlagDescription, argDescriptions []ArgDescription) (err error) {
+	argById := make(map[string]ArgDescription)
+	for _, desc := range argDescriptions {
+		argById[desc.Id] = desc
+	}
+	isFlag := make(map[string]bool)
+	for _, desc := range flagDescriptions {
+		isFlag[desc.Id] = true
+	}
+
+	var optionalArg *types.Var
+	signature := f.Type().(*types.Signature)
+	params := signature.Params()
+	for i := 0; i < params.Len(); i++ {
+		param := params.At(i)
+		isVariadic := signature.Variadic() && i == params.Len()-1
+		desc, isArg := argById[param.Name()]
+		if !isArg && !isVariadic {
+			continue
+		}
+		switch {
+		case gh.isGoatContext(param.Type()):
+			gh.reportErrorAt(param.Pos(), fmt.Sprintf("%s is a goat.Context, and can't be a positional argument", param.Name()))
+			err = errors.New("Context used as an argument")
+		case isFlag[param.Name()] && isVariadic:
+			gh.reportErrorAt(param.Pos(), fmt.Sprintf("%s is variadic, and can't be a flag", param.Name()))
+			err = errors.New("Variadic parameter used as a flag")
+		case isFlag[param.Name()]:
+			gh.reportErrorAt(param.Pos(), fmt.Sprintf("%s is described both as a flag and as a positional argument", param.Name()))
+			err = errors.New("Parameter is both a flag and an argument")
+		case isVariadic && desc.Default != nil:
+			gh.reportErrorAt(param.Pos(), fmt.Sprintf("%s is variadic, and can't have a default value", param.Name()))
+			err = errors.New("Default value for a variadic argument")
+		case isVariadic:
+			// Variadic arguments are always last, so there's nothing more to check.
+		case isRepeatedType(desc.Type):
+			gh.reportErrorAt(param.Pos(), fmt.Sprintf("%s is a %s, and slice and map parameters can't be positional arguments", param.Name(), desc.Type))
+			err = errors.New("Repeated type used as an argument")
+		case desc.Default != nil || strings.HasPrefix(desc.Type, "*"):
+			optionalArg = param
+		case optionalArg != nil:
+			gh.reportErrorAt(param.Pos(), fmt.Sprintf("required argument %s follows optional argument %s", param.Name(), optionalArg.Name()))
+			err = errors.New("Required argument after an optional one")
+		}
+	}
+	return err
+}
+
+// checkGroups makes sure that struct parameters are not described as flags or arguments,
+// as their fields are described by struct tags instead.
+func (gh *Goatherd) checkGroups(signature GoatSignature, f *types.Func, flagDescriptions []FlagDescription, argDescriptions []ArgDescription) (err error) {
+	isDescribed := make(map[string]bool)
+	for _, desc := range flagDescriptions {
+		isDescribed[desc.Id] = true
+	}
+	for _, desc := range argDescriptions {
+		isDescribed[desc.Id] = true
+	}
+	params := f.Type().(*types.Signature).Params()
+	for i, arg := range signature.Args {
+		if len(arg.Fields) != 0 && isDescribed[arg.Name] {
+			gh.reportErrorAt(params.At(i).Pos(), fmt.Sprintf("%s is a group of flags, described by the struct tags of %s", arg.Name, arg.Type))
+			err = errors.New("Flag group used as a flag")
+		}
+	}
+	return err
+}
+
+// helpFlagNames are the names of the help flag cli adds to every command.
+var helpFlagNames = []string{"help", "h"}
+
+// checkFlagNames makes sure that the names and aliases of the flags of a function don't collide,
+// either with each other or with the help flag.
+//
+// Names that are not constants can't be checked, and are skipped.
+// Counting flags without aliases get the first letter of their name as an alias, if it is free.
+func (gh *Goatherd) checkFlagNames(signature GoatSignature, f *types.Func, flagDescriptions []FlagDescription, argDescriptions []ArgDescription) (err error) {
+	isArg := make(map[string]bool)
+	for _, desc := range argDescriptions {
+		isArg[desc.Id] = true
+	}
+	descIndexById := make(map[string]int)
+	for i, desc := range flagDescriptions {
+		descIndexById[desc.Id] = i
+	}
+
+	declared := make(map[string]FlagName)
+	declare := func(name FlagName) {
+		if Contains(helpFlagNames, name.Name) {
+			gh.reportErrorAt(name.Pos, fmt.Sprintf("%q is already used by the help flag", name.Name))
+			err = errors.New("Flag name collides with the help flag")
+			return
+		}
+		if previous, exists := declared[name.Name]; exists {
+			gh.reportErrorAt(name.Pos, fmt.Sprintf("%q is already declared at %s", name.Name, gh.pkg.Fset.Position(previous.Pos)))
+			gh.reportErrorAt(previous.Pos, fmt.Sprintf("%q is declared again at %s", name.Name, gh.pkg.Fset.Position(name.Pos)))
+			err = errors.New("Flag name collision")
+			return
+		}
+		declared[name.Name] = name
+	}
+
+	var countFlags []int
+	params := f.Type().(*types.Signature).Params()
+	for i, arg := range signature.Args {
+		param := params.At(i)
+		if arg.IsContext || arg.IsVariadic || isArg[arg.Name] {
+			continue
+		}
+		if len(arg.Fields) != 0 {
+			for _, field := range arg.Fields {
+				name, _ := strconv.Unquote(field.FlagName)
+				declare(FlagName{Name: name, Pos: field.Pos})
+			}
+			continue
+		}
+		index, isDescribed := descIndexById[arg.Name]
+		if !isDescribed {
+			declare(FlagName{Name: arg.Name, Pos: param.Pos()})
+			continue
+		}
+		desc := flagDescriptions[index]
+		if desc.ConstName != nil {
+			declare(*desc.ConstName)
+		} else if desc.NameExpr == nil {
+			declare(FlagName{Name: arg.Name, Pos: param.Pos()})
+		}
+		for _, alias := range desc.Aliases {
+			declare(alias)
+		}
+		if desc.Count && len(desc.Aliases) == 0 {
+			countFlags = append(countFlags, index)
+		}
+	}
+
+	for _, index := range countFlags {
+		desc := &flagDescriptions[index]
+		name := desc.Id
+		if desc.ConstName != nil {
+			name = desc.ConstName.Name
+		} else if desc.NameExpr != nil {
+			continue
+		}
+		short := string([]rune(name)[:1])
+		if _, exists := declared[short]; exists || Contains(helpFlagNames, short) {
+			continue
+		}
+		declared[short] = FlagName{Name: short}
+		desc.Aliases = append(desc.Aliases, declared[short])
+	}
+	return err
+}
+
+// checkDefaultValue makes sure that constant default values can be converted to
+// the type of the flag without overflowing.
+//
+// Default values are passed as `any`, so the compiler can't check them for us.
+func (gh *Goatherd) checkDefaultValue(flagExpr ast.Expr, defaultExpr ast.Expr) error {
+	flagType := gh.pkg.TypesInfo.TypeOf(flagExpr)
+	if flagType == nil {
+		return nil
+	}
+	basic, isBasic := flagType.Underlying().(*types.Basic)
+	if !isBasic || basic.Info()&types.IsNumeric == 0 {
+		return nil
+	}
+	defaultValue := gh.pkg.TypesInfo.Types[defaultExpr].Value
+	if defaultValue == nil {
+		// Not a constant, nothing to check.
+		return nil
+	}
+	typeName := gh.typeString(flagType)
+	// We convert to the underlying type, as it is always in scope.
+	conversion := fmt.Sprintf("%s(%s)", basic.Name(), constantLiteral(defaultValue))
+	_, err := types.Eval(gh.pkg.Fset, gh.pkg.Types, token.NoPos, conversion)
+	if err != nil {
+		message := err.Error()
+		if typeErr, isTypeErr := err.(types.Error); isTypeErr {
+			message = typeErr.Msg
+		}
+		gh.reportError(defaultExpr, fmt.Sprintf("Invalid default value for %s flag: %s", typeName, message))
+		return errors.Wrap(err, "Invalid default value")
+	}
+	return nil
+}
+
+// constantLiteral formats a constant value as an exact literal.
+//
+// constant.Value.ExactString formats fractions as `n/d`, which is an integer division,
+// so floats are formatted as float literals, or with a float numerator.
+func constantLiteral(value constant.Value) string {
+	if value.Kind() != constant.Float {
+		return value.ExactString()
+	}
+	if f, isExact := constant.Float64Val(value); isExact {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return strings.Replace(value.ExactString(), "/", ".0/", 1)
+}
+
+// checkCompleteFunc makes sure that the argument of .Complete(fn) is a named function
+// with the func(ctx *goat.Context, prefix string) []string signature.
+func (gh *Goatherd) checkCompleteFunc(fnExpr ast.Expr) error {
+	var ident *ast.Ident
+	switch expr := fnExpr.(type) {
+	case *ast.Ident:
+		ident = expr
+	case *ast.SelectorExpr:
+		ident = expr.Sel
+	}
+	if ident == nil {
+		gh.reportError(fnExpr, "Expected a named function for .Complete(fn)")
+		return errors.New("Unnamed completion function")
+	}
+	fn, isFunc := gh.pkg.TypesInfo.Uses[ident].(*types.Func)
+	if !isFunc {
+		gh.reportError(fnExpr, "Expected a named function for .Complete(fn)")
+		return errors.New("Unnamed completion function")
+	}
+	signature := fn.Type().(*types.Signature)
+	params, results := signature.Params(), signature.Results()
+	stringType := types.Typ[types.String]
+	if signature.Recv() != nil || params.Len() != 2 || results.Len() != 1 ||
+		!gh.isGoatContext(params.At(0).Type()) || !types.Identical(params.At(1).Type(), stringType) ||
+		!types.Identical(results.At(0).Type(), types.NewSlice(stringType)) {
+		gh.reportError(fnExpr, "Expected a func(ctx *goat.Context, prefix string) []string for .Complete(fn)")
+		return errors.New("Invalid completion function")
+	}
+	return nil
+}
+
+// checkValidator makes sure that a validation directive fits the type of the flag,
+// and that its constant arguments are valid values.
+func (gh *Goatherd) checkValidator(flagExpr ast.Expr, call FluentCall) error {
+	flagType := gh.pkg.TypesInfo.TypeOf(flagExpr)
+	if flagType == nil {
+		return nil
+	}
+	valueType := flagType
+	if pointer, isPointer := flagType.Underlying().(*types.Pointer); isPointer {
+		valueType = pointer.Elem()
+	}
+	basic, isBasic := valueType.Underlying().(*types.Basic)
+	signature := validatorSignatures[call.Name]
+	reportTypeError := func(kind string) error {
+		gh.reportError(call.Ident, fmt.Sprintf("%s can only be used with %s flags, not %s", signature, kind, gh.typeString(flagType)))
+		return errors.New("Validator used with the wrong type")
+	}
+
+	var err error
+	switch call.Name {
+	case "Range":
+		if !isBasic || basic.Info()&types.IsNumeric == 0 {
+			return reportTypeError("numeric")
+		}
+		for _, arg := range call.Args {
+			if argErr := gh.checkConstantValue(arg, basic, signature); argErr != nil {
+				err = argErr
+			}
+		}
+		if err == nil && len(call.Args) == 2 {
+			min, max := gh.pkg.TypesInfo.Types[call.Args[0]].Value, gh.pkg.TypesInfo.Types[call.Args[1]].Value
+			if min != nil && max != nil && constant.Compare(min, token.GTR, max) {
+				gh.reportError(call.Ident, fmt.Sprintf("Invalid range for %s: %s is greater than %s", signature, min, max))
+				err = errors.New("Invalid range")
+			}
+		}
+	case "MinLen":
+		switch valueType.Underlying().(type) {
+		case *types.Slice, *types.Map:
+		default:
+			if !isBasic || basic.Info()&types.IsString == 0 {
+				return reportTypeError("string, slice and map")
+			}
+		}
+		if len(call.Args) == 1 {
+			err = gh.checkConstantValue(call.Args[0], types.Typ[types.Int], signature)
+		}
+	case "Pattern":
+		if !isBasic || basic.Info()&types.IsString == 0 {
+			return reportTypeError("string")
+		}
+		if len(call.Args) == 1 {
+			pattern := gh.pkg.TypesInfo.Types[call.Args[0]].Value
+			if pattern != nil && pattern.Kind() == constant.String {
+				if _, patternErr := regexp.Compile(constant.StringVal(pattern)); patternErr != nil {
+					gh.reportError(call.Args[0], fmt.Sprintf("Invalid pattern for %s: %s", signature, patternErr))
+					err = errors.Wrap(patternErr, "Invalid pattern")
+				}
+			}
+		}
+	case "OneOf":
+		if !isBasic {
+			return reportTypeError("string, numeric and boolean")
+		}
+		for _, arg := range call.Args {
+			if argErr := gh.checkConstantValue(arg, basic, signature); argErr != nil {
+				err = argErr
+			}
+		}
+	case "Validate":
+		if len(call.Args) != 1 {
+			return nil
+		}
+		fnType, isFunc := gh.pkg.TypesInfo.TypeOf(call.Args[0]).(*types.Signature)
+		errorType := types.Universe.Lookup("error").Type()
+		if !isFunc || fnType.Params().Len() != 1 || fnType.Results().Len() != 1 ||
+			!types.Identical(fnType.Params().At(0).Type(), flagType) || !types.Identical(fnType.Results().At(0).Type(), errorType) {
+			gh.reportError(call.Args[0], fmt.Sprintf("Expected a func(%s) error for %s", gh.typeString(flagType), signature))
+			err = errors.New("Invalid validation function")
+		}
+	}
+	return err
+}
+
+// checkConstantValue makes sure that a constant argument of a directive can be converted to a basic type.
+//
+// Non-constant arguments are left for the compiler to check.
+func (gh *Goatherd) checkConstantValue(expr ast.Expr, basic *types.Basic, signature string) error {
+	value := gh.pkg.TypesInfo.Types[expr].Value
+	if value == nil {
+		return nil
+	}
+	_, err := types.Eval(gh.pkg.Fset, gh.pkg.Types, token.NoPos, fmt.Sprintf("%s(%s)", basic.Name(), constantLiteral(value)))
+	if err != nil {
+		message := err.Error()
+		if typeErr, isTypeErr := err.(types.Error); isTypeErr {
+			message = typeErr.Msg
+		}
+		gh.reportError(expr, fmt.Sprintf("Invalid value for %s: %s", signature, message))
+		return errors.Wrap(err, "Invalid value")
+	}
+	return nil
+}
+
 func (gh *Goatherd) findFuncDecl(f *types.Func) *ast.FuncDecl {
 	var fdecl *ast.FuncDecl
 	// Weird hack to get the package containing the function
@@ -378,40 +1145,101 @@ func formatSource(src string) string {
 }
 
 type Flag struct {
-	Type      string
-	Name      string
-	Usage     string
-	Default   string
+	Type    string
+	Name    string
+	Usage   string
+	Default string
+	// Options holds the flags.Option expressions passed when creating the flag.
+	Options   []string
 	IsContext bool
+	// IsArg is true for positional arguments, which are taken by their Position instead of their Name.
+	IsArg      bool
+	Position   int
+	IsVariadic bool
+	// Fields holds the flags of a struct parameter, named after the struct fields by FieldName.
+	Fields    []Flag
+	FieldName string
+	// Validators holds the flags.Option expressions of the flag's validators,
+	// which are passed both when creating the flag and when validating it.
+	Validators []string
+}
+
+// Check is a call to a flags.CheckXXX function, checking a constraint on a group of flags.
+type Check struct {
+	Function string
+	// Names are the names of the flags, as Go expressions.
+	Names []string
 }
+
 type Action struct {
 	Function string
 	Flags    []Flag
 	Name     string
 	Usage    string
 	NoError  bool
+	Hidden   bool
+	Category string
+	// Deprecated is the deprecation message, or an empty string if the action is not deprecated.
+	Deprecated string
+	// Checks are the checks of flag constraints, done before calling the function.
+	Checks []Check
+	// HasValidators is true if any of the flags has validators.
+	HasValidators bool
+	// MinArgs and MaxArgs are the allowed numbers of positional arguments.
+	// MaxArgs is -1 if there is a variadic argument.
+	MinArgs int
+	MaxArgs int
 }
 
-func isGoatContext(typeName string) bool {
-	goatContextType := reflect.TypeOf(*new(goat.Context))
-	goatContextTypeName := "*" + goatContextType.PkgPath() + "." + goatContextType.Name()
-	return typeName == goatContextTypeName
+// contextBackend returns the backend whose Context a type points to, if it is a pointer to a Context.
+func contextBackend(typ types.Type) (string, bool) {
+	for backend, contextType := range contextTypes {
+		if typ.String() == "*"+contextType.PkgPath()+"."+contextType.Name() {
+			return backend, true
+		}
+	}
+	return "", false
+}
+
+// isGoatContext reports whether a type is a pointer to the Context of the backend the code is generated for.
+func (gh *Goatherd) isGoatContext(typ types.Type) bool {
+	backend, isContext := contextBackend(typ)
+	return isContext && backend == gh.backend
 }
 
-func makeAction(functionName string, signature GoatSignature, actionDescription ActionDescription, flagDescriptions []FlagDescription) Action {
+func makeAction(functionName string, signature GoatSignature, actionDescription ActionDescription, flagDescriptions []FlagDescription, argDescriptions []ArgDescription, constraints []FlagConstraint) Action {
 	flagByArgName := make(map[string]Flag)
 	for _, arg := range signature.Args {
+		name := "\"" + arg.Name + "\""
+		if arg.IsVariadic {
+			name = strconv.Quote(strings.ToUpper(arg.Name))
+		}
+		var fields []Flag
+		for _, field := range arg.Fields {
+			fields = append(fields, Flag{
+				Type:      field.Type,
+				Name:      field.FlagName,
+				Usage:     field.Usage,
+				Default:   field.Default,
+				Options:   field.Options,
+				FieldName: field.Name,
+			})
+		}
 		flagByArgName[arg.Name] = Flag{
-			Type:      arg.Type,
-			Name:      "\"" + arg.Name + "\"",
-			Default:   "nil",
-			Usage:     "\"\"",
-			IsContext: isGoatContext(arg.Type),
+			Type:       arg.Type,
+			Name:       name,
+			Default:    "nil",
+			Usage:      "\"\"",
+			Options:    arg.Options,
+			IsContext:  arg.IsContext,
+			IsArg:      arg.IsVariadic,
+			IsVariadic: arg.IsVariadic,
+			Fields:     fields,
 		}
 	}
 	for _, desc := range flagDescriptions {
 		typ := desc.Type
-		if isGoatContext(typ) {
+		if flagByArgName[desc.Id].IsContext {
 			// goat.Context is not a flag type, it's always the context object.
 			continue
 		}
@@ -427,17 +1255,95 @@ func makeAction(functionName string, signature GoatSignature, actionDescription
 		if desc.Default != nil {
 			default_ = *desc.Default
 		}
+		options := append([]string{}, flagByArgName[desc.Id].Options...)
+		if desc.Layout != nil {
+			options = append(options, "flags.Layout("+*desc.Layout+")")
+		}
+		if desc.Separator != nil {
+			options = append(options, "flags.Separator("+*desc.Separator+")")
+		}
+		if desc.Count {
+			options = append(options, "flags.Count()")
+		}
+		if desc.File {
+			options = append(options, "flags.File()")
+		}
+		if desc.Complete != nil {
+			options = append(options, "flags.Complete(goat.CompleteFunc("+*desc.Complete+"))")
+		}
+		if len(desc.Aliases) != 0 {
+			aliases := make([]string, len(desc.Aliases))
+			for i, alias := range desc.Aliases {
+				aliases[i] = strconv.Quote(alias.Name)
+			}
+			options = append(options, "flags.Alias("+strings.Join(aliases, ", ")+")")
+		}
+		if len(desc.EnvVars) != 0 {
+			options = append(options, "flags.EnvVar("+strings.Join(desc.EnvVars, ", ")+")")
+		}
+		if desc.Category != nil {
+			options = append(options, "flags.Category("+*desc.Category+")")
+		}
+		if desc.Hidden {
+			options = append(options, "flags.Hidden()")
+		}
+		if desc.Deprecated != nil {
+			options = append(options, "flags.Deprecated("+*desc.Deprecated+")")
+		}
+		flagByArgName[desc.Id] = Flag{
+			Type:       typ,
+			Name:       name,
+			Usage:      usage,
+			Default:    default_,
+			Options:    options,
+			IsContext:  false,
+			Validators: desc.Validators,
+		}
+	}
+	isArg := make(map[string]bool)
+	for _, desc := range argDescriptions {
+		isArg[desc.Id] = true
+		name := strconv.Quote(strings.ToUpper(desc.Id))
+		if desc.Name != nil {
+			name = *desc.Name
+		}
+		usage := "\"\""
+		if desc.Usage != nil {
+			usage = *desc.Usage
+		}
+		default_ := "nil"
+		if desc.Default != nil {
+			default_ = *desc.Default
+		}
+		typ := desc.Type
+		if flagByArgName[desc.Id].IsVariadic {
+			// The description has the slice type, and we need the element type.
+			typ = flagByArgName[desc.Id].Type
+		}
 		flagByArgName[desc.Id] = Flag{
-			Type:      typ,
-			Name:      name,
-			Usage:     usage,
-			Default:   default_,
-			IsContext: false,
+			Type:       typ,
+			Name:       name,
+			Usage:      usage,
+			Default:    default_,
+			Options:    flagByArgName[desc.Id].Options,
+			IsArg:      true,
+			IsVariadic: flagByArgName[desc.Id].IsVariadic,
 		}
 	}
 	var flags []Flag
+	minArgs, maxArgs := 0, 0
 	for _, arg := range signature.Args {
 		flag := flagByArgName[arg.Name]
+		if flag.IsVariadic {
+			flag.Position = maxArgs
+			maxArgs = -1
+		} else if flag.IsArg {
+			flag.Position = maxArgs
+			maxArgs++
+			if flag.Default == "nil" && !strings.HasPrefix(flag.Type, "*") {
+				minArgs++
+			}
+		}
 		flags = append(flags, flag)
 	}
 
@@ -450,12 +1356,39 @@ func makeAction(functionName string, signature GoatSignature, actionDescription
 		usage = *actionDescription.Usage
 	}
 
+	hasValidators := false
+	for _, flag := range flags {
+		hasValidators = hasValidators || len(flag.Validators) != 0
+	}
+	var checks []Check
+	for _, constraint := range constraints {
+		check := Check{Function: "flags.Check" + constraint.Kind}
+		for _, id := range constraint.Ids {
+			check.Names = append(check.Names, flagByArgName[id.Name].Name)
+		}
+		checks = append(checks, check)
+	}
+	deprecated := ""
+	if actionDescription.Deprecated != nil {
+		deprecated = *actionDescription.Deprecated
+	}
+	category := ""
+	if actionDescription.Category != nil {
+		category = *actionDescription.Category
+	}
 	return Action{
-		Function: functionName,
-		Flags:    flags,
-		Name:     name,
-		Usage:    usage,
-		NoError:  signature.NoError,
+		Function:      functionName,
+		Flags:         flags,
+		Name:          name,
+		Usage:         usage,
+		NoError:       signature.NoError,
+		Hidden:        actionDescription.Hidden,
+		Category:      category,
+		Deprecated:    deprecated,
+		Checks:        checks,
+		HasValidators: hasValidators,
+		MinArgs:       minArgs,
+		MaxArgs:       maxArgs,
 	}
 }
 
@@ -497,25 +1430,66 @@ func (gh *Goatherd) createAction(actionFunc actionDefinition) (Action, error) {
 	fdecl := gh.findFuncDecl(actionFunc.Func)
 	signature, err := gh.parseSignature(actionFunc.Func)
 	if err != nil {
-		log.Fatal(err)
+		return Action{}, err
 	}
 	actionDescription, err := gh.parseActionDescription(fdecl)
 	if err != nil {
-		log.Fatal(err)
+		return Action{}, err
 	}
 	flagDescriptions, err := gh.parseFlagDescriptions(fdecl)
 	if err != nil {
-		log.Fatal(err)
+		return Action{}, err
+	}
+	argDescriptions, err := gh.parseArgDescriptions(fdecl)
+	if err != nil {
+		return Action{}, err
+	}
+	err = gh.checkArgs(actionFunc.Func, flagDescriptions, argDescriptions)
+	if err != nil {
+		return Action{}, err
+	}
+	err = gh.checkGroups(signature, actionFunc.Func, flagDescriptions, argDescriptions)
+	if err != nil {
+		return Action{}, err
+	}
+	err = gh.checkFlagNames(signature, actionFunc.Func, flagDescriptions, argDescriptions)
+	if err != nil {
+		return Action{}, err
+	}
+	constraints, err := gh.parseFlagConstraints(fdecl)
+	if err != nil {
+		return Action{}, err
+	}
+	err = gh.checkFlagConstraints(signature, actionFunc.Func, constraints, argDescriptions)
+	if err != nil {
+		return Action{}, err
 	}
 	functionName, err := formatNode(gh.pkg.Fset, actionFunc.Def)
 	if err != nil {
-		log.Fatal(err)
+		return Action{}, err
 	}
-	return makeAction(functionName, signature, actionDescription, flagDescriptions), nil
+	action := makeAction(functionName, signature, actionDescription, flagDescriptions, argDescriptions, constraints)
+	if gh.backend == stdflagBackend {
+		err = gh.checkStdflagAction(actionFunc.Func, action, constraints)
+		if err != nil {
+			return Action{}, err
+		}
+	}
+	return action, nil
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "compat" {
+		os.Exit(compat(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
+	backend, err := parseBackend(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	gh := NewGoatherd(loadPackages())
+	gh.backend = backend
 	var actions []Action
 	usedImports := make(map[string]bool)
 	for _, actionFuncDefinition := range gh.findActionFunctions() {
@@ -547,6 +1521,12 @@ func main() {
 		"\"github.com/tmr232/goat/flags\"",
 		"\"github.com/urfave/cli/v2\"",
 	}
+	templateName := "goat-file"
+	if backend == stdflagBackend {
+		// The generated code only depends on the runtime, named goat like the urfave/cli runtime.
+		baseImports = []string{runtimeImport(backend)}
+		templateName = "stdflag-file"
+	}
 
 	imports := append([]string{}, baseImports...)
 	for path, name := range importsByPath {
@@ -556,6 +1536,14 @@ func main() {
 			imports = append(imports, *name+" "+path)
 		}
 	}
+	for spec := range gh.extraImports {
+		if spec.Path == runtimePaths[backend] {
+			continue
+		}
+		if !Contains(imports, spec.String()) {
+			imports = append(imports, spec.String())
+		}
+	}
 
 	data := struct {
 		Package string
@@ -566,7 +1554,7 @@ func main() {
 		Actions: actions,
 		Imports: imports,
 	}
-	file, err := gh.Render("goat-file", data)
+	file, err := gh.Render(templateName, data)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,607 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"golang.org/x/tools/go/packages"
+	"strings"
+	"testing"
+)
+
+// testFset and testImporter are shared by the tests, so that the imported packages are only type-checked once.
+var testFset = token.NewFileSet()
+var testImporter = importer.ForCompiler(testFset, "source", nil)
+
+// loadSource loads the source of a main.go file the way goater loads a package,
+// returning a Goatherd generating code for the backend, and reporting its errors to the returned buffer.
+func loadSource(t *testing.T, backend string, src string) (*Goatherd, *bytes.Buffer) {
+	t.Helper()
+	file, err := parser.ParseFile(testFset, "main.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{
+		Types:     make(map[ast.Expr]types.TypeAndValue),
+		Defs:      make(map[*ast.Ident]types.Object),
+		Uses:      make(map[*ast.Ident]types.Object),
+		Instances: make(map[*ast.Ident]types.Instance),
+	}
+	config := types.Config{Importer: testImporter}
+	typesPkg, err := config.Check("main", testFset, []*ast.File{file}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := &packages.Package{
+		Name:      typesPkg.Name(),
+		PkgPath:   typesPkg.Path(),
+		Fset:      testFset,
+		Syntax:    []*ast.File{file},
+		Types:     typesPkg,
+		TypesInfo: info,
+		Imports:   make(map[string]*packages.Package),
+	}
+	for _, imported := range typesPkg.Imports() {
+		pkg.Imports[imported.Path()] = &packages.Package{Name: imported.Name(), PkgPath: imported.Path(), Types: imported}
+	}
+	gh := NewGoatherd(pkg)
+	gh.backend = backend
+	var output bytes.Buffer
+	gh.output = &output
+	return gh, &output
+}
+
+// createActions creates the actions of the source, returning the errors reported by goater.
+func createActions(t *testing.T, backend string, src string) (string, error) {
+	t.Helper()
+	gh, output := loadSource(t, backend, src)
+	for _, actionFunc := range gh.findActionFunctions() {
+		if _, err := gh.createAction(actionFunc); err != nil {
+			return output.String(), err
+		}
+	}
+	return output.String(), nil
+}
+
+// checkErrors creates the actions of the source, making sure that goater reports exactly the given errors.
+func checkErrors(t *testing.T, backend string, src string, want ...string) {
+	t.Helper()
+	output, err := createActions(t, backend, src)
+	if len(want) == 0 && err != nil {
+		t.Errorf("createAction() error = %v, reported:\n%s", err, output)
+	}
+	if len(want) != 0 && err == nil {
+		t.Errorf("createAction() succeeded, want errors:\n%s", strings.Join(want, "\n"))
+	}
+	got := strings.Split(strings.TrimSpace(output), "\n")
+	if output == "" {
+		got = nil
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("reported:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestRegisteredTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"registered type", `package main
+
+import (
+	"github.com/tmr232/goat"
+	"github.com/tmr232/goat/flags"
+)
+
+type Celsius float64
+
+var handler flags.TypeHandler
+
+func init() {
+	flags.RegisterTypeHandler[Celsius](handler)
+}
+
+func app(temperature Celsius) {}
+
+func main() {
+	goat.Run(app)
+}
+`, nil},
+		{"unregistered type", `package main
+
+import "github.com/tmr232/goat"
+
+type Celsius float64
+
+func app(temperature Celsius) {}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{"main.go:7:10 Error: Unsupported flag type Celsius for temperature: no type handler, and it does not implement encoding.TextUnmarshaler or flag.Value"}},
+		{"registered struct type", `package main
+
+import (
+	"github.com/tmr232/goat"
+	"github.com/tmr232/goat/flags"
+)
+
+type Point struct {
+	X, Y int
+}
+
+var handler flags.TypeHandler
+
+func init() {
+	flags.RegisterTypeHandler[Point](handler)
+}
+
+func app(origin Point) {}
+
+func main() {
+	goat.Run(app)
+}
+`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkErrors(t, cliBackend, tt.src, tt.want...)
+		})
+	}
+}
+
+func TestCheckFlagNames(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"distinct names", `package main
+
+import "github.com/tmr232/goat"
+
+func app(verbose bool, version bool) {
+	goat.Flag(verbose).Alias("V")
+	goat.Flag(version).Name("show-version")
+}
+
+func main() {
+	goat.Run(app)
+}
+`, nil},
+		{"alias collides with a name", `package main
+
+import "github.com/tmr232/goat"
+
+func app(verbose bool, version bool) {
+	goat.Flag(version).Alias("verbose")
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{
+			`main.go:6:27 Error: "verbose" is already declared at main.go:5:10`,
+			`main.go:5:10 Error: "verbose" is declared again at main.go:6:27`,
+		}},
+		{"aliases collide", `package main
+
+import "github.com/tmr232/goat"
+
+func app(verbose bool, version bool) {
+	goat.Flag(verbose).Alias("v")
+	goat.Flag(version).Alias("v")
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{
+			`main.go:7:27 Error: "v" is already declared at main.go:6:27`,
+			`main.go:6:27 Error: "v" is declared again at main.go:7:27`,
+		}},
+		{"renamed flag collides with a name", `package main
+
+import "github.com/tmr232/goat"
+
+func app(verbose bool, loud bool) {
+	goat.Flag(loud).Name("verbose")
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{
+			`main.go:6:23 Error: "verbose" is already declared at main.go:5:10`,
+			`main.go:5:10 Error: "verbose" is declared again at main.go:6:23`,
+		}},
+		{"names of the help flag", `package main
+
+import "github.com/tmr232/goat"
+
+func app(h bool, verbose bool) {
+	goat.Flag(verbose).Alias("help")
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{
+			`main.go:5:10 Error: "h" is already used by the help flag`,
+			`main.go:6:27 Error: "help" is already used by the help flag`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkErrors(t, cliBackend, tt.src, tt.want...)
+		})
+	}
+}
+
+func TestCheckDefaultValue(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"valid defaults", `package main
+
+import "github.com/tmr232/goat"
+
+func app(retries int8, ratio float32, port uint16) {
+	goat.Flag(retries).Default(3)
+	goat.Flag(ratio).Default(0.1)
+	goat.Flag(port).Default(8080)
+}
+
+func main() {
+	goat.Run(app)
+}
+`, nil},
+		{"overflow", `package main
+
+import "github.com/tmr232/goat"
+
+func app(retries int8) {
+	goat.Flag(retries).Default(300)
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{"main.go:6:29 Error: Invalid default value for int8 flag: constant 300 overflows int8"}},
+		{"negative unsigned", `package main
+
+import "github.com/tmr232/goat"
+
+func app(port uint16) {
+	goat.Flag(port).Default(-1)
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{"main.go:6:26 Error: Invalid default value for uint16 flag: constant -1 overflows uint16"}},
+		{"truncated float", `package main
+
+import "github.com/tmr232/goat"
+
+func app(count int) {
+	goat.Flag(count).Default(2.5)
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{"main.go:6:27 Error: Invalid default value for int flag: cannot convert 2.5 (untyped float constant) to type int"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkErrors(t, cliBackend, tt.src, tt.want...)
+		})
+	}
+}
+
+func TestCheckFlagConstraints(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"flags", `package main
+
+import "github.com/tmr232/goat"
+
+func app(json bool, yaml bool, user *string, password *string) {
+	goat.Exclusive(json, yaml)
+	goat.Together(user, password)
+}
+
+func main() {
+	goat.Run(app)
+}
+`, nil},
+		{"single flag", `package main
+
+import "github.com/tmr232/goat"
+
+func app(json bool) {
+	goat.Exclusive(json)
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{"main.go:6:7 Error: Expected at least two flags for goat.Exclusive"}},
+		{"not a parameter", `package main
+
+import "github.com/tmr232/goat"
+
+var yaml bool
+
+func app(json bool) {
+	goat.Exclusive(json, yaml)
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{"main.go:8:23 Error: yaml is not a parameter of app"}},
+		{"not flags", `package main
+
+import "github.com/tmr232/goat"
+
+func app(ctx *goat.Context, src string, json bool) {
+	goat.Arg(src)
+	goat.Together(ctx, json)
+	goat.Exclusive(src, json)
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{
+			"main.go:7:16 Error: ctx is not a flag, and can't be used in goat.Together",
+			"main.go:8:17 Error: src is not a flag, and can't be used in goat.Exclusive",
+		}},
+		{"group of flags", `package main
+
+import "github.com/tmr232/goat"
+
+type Options struct {
+	Host string
+}
+
+func app(opts Options, json bool) {
+	goat.Together(opts, json)
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{"main.go:10:16 Error: opts is a group of flags, and can't be used in goat.Together"}},
+		{"repeated flag", `package main
+
+import "github.com/tmr232/goat"
+
+func app(json bool, yaml bool) {
+	goat.Exclusive(json, yaml, json)
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{"main.go:6:29 Error: json is used more than once in goat.Exclusive"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkErrors(t, cliBackend, tt.src, tt.want...)
+		})
+	}
+}
+
+func TestCheckValidator(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"valid validators", `package main
+
+import "github.com/tmr232/goat"
+
+func checkRatio(ratio float64) error {
+	return nil
+}
+
+func app(port int, name string, format string, tags []string, ratio float64) {
+	goat.Flag(port).Range(1, 65535)
+	goat.Flag(name).MinLen(1).Pattern("^[a-z]+$")
+	goat.Flag(format).OneOf("json", "yaml")
+	goat.Flag(tags).MinLen(1)
+	goat.Flag(ratio).Range(0, 0.5).Validate(checkRatio)
+}
+
+func main() {
+	goat.Run(app)
+}
+`, nil},
+		{"wrong flag types", `package main
+
+import "github.com/tmr232/goat"
+
+func app(name string, verbose bool, tags []string) {
+	goat.Flag(name).Range(1, 10)
+	goat.Flag(verbose).MinLen(1)
+	goat.Flag(tags).OneOf("a", "b")
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{
+			"main.go:6:18 Error: .Range(min, max) can only be used with numeric flags, not string",
+			"main.go:7:21 Error: .MinLen(minLen) can only be used with string, slice and map flags, not bool",
+			"main.go:8:18 Error: .OneOf(values...) can only be used with string, numeric and boolean flags, not []string",
+		}},
+		{"invalid values", `package main
+
+import "github.com/tmr232/goat"
+
+func app(retries int8, count int, level int) {
+	goat.Flag(retries).Range(0, 300)
+	goat.Flag(count).Range(0.5, 10)
+	goat.Flag(level).OneOf(1, "high")
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{
+			"main.go:6:30 Error: Invalid value for .Range(min, max): constant 300 overflows int8",
+			"main.go:7:25 Error: Invalid value for .Range(min, max): cannot convert 0.5 (untyped float constant) to type int",
+			"main.go:8:28 Error: Invalid value for .OneOf(values...): cannot convert \"high\" (untyped string constant) to type int",
+		}},
+		{"invalid range", `package main
+
+import "github.com/tmr232/goat"
+
+func app(port int) {
+	goat.Flag(port).Range(10, 1)
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{"main.go:6:18 Error: Invalid range for .Range(min, max): 10 is greater than 1"}},
+		{"invalid pattern", `package main
+
+import "github.com/tmr232/goat"
+
+func app(name string) {
+	goat.Flag(name).Pattern("[a-z")
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{"main.go:6:26 Error: Invalid pattern for .Pattern(pattern): error parsing regexp: missing closing ]: `[a-z`"}},
+		{"wrong validation function", `package main
+
+import "github.com/tmr232/goat"
+
+func checkName(name string) bool {
+	return name != ""
+}
+
+func app(name string) {
+	goat.Flag(name).Validate(checkName)
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{"main.go:10:27 Error: Expected a func(string) error for .Validate(fn)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkErrors(t, cliBackend, tt.src, tt.want...)
+		})
+	}
+}
+
+func TestCheckCompleteFunc(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"completion function", `package main
+
+import "github.com/tmr232/goat"
+
+func completeHosts(ctx *goat.Context, prefix string) []string {
+	return nil
+}
+
+func app(host string) {
+	goat.Flag(host).Complete(completeHosts)
+}
+
+func main() {
+	goat.Run(app)
+}
+`, nil},
+		{"function literal", `package main
+
+import "github.com/tmr232/goat"
+
+func app(host string) {
+	goat.Flag(host).Complete(func(ctx *goat.Context, prefix string) []string { return nil })
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{"main.go:6:27 Error: Expected a named function for .Complete(fn)"}},
+		{"function variable", `package main
+
+import "github.com/tmr232/goat"
+
+var completeHosts = func(ctx *goat.Context, prefix string) []string { return nil }
+
+func app(host string) {
+	goat.Flag(host).Complete(completeHosts)
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{"main.go:8:27 Error: Expected a named function for .Complete(fn)"}},
+		{"wrong signature", `package main
+
+import "github.com/tmr232/goat"
+
+func completeHosts(prefix string) []string {
+	return nil
+}
+
+func app(host string) {
+	goat.Flag(host).Complete(completeHosts)
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{"main.go:10:27 Error: Expected a func(ctx *goat.Context, prefix string) []string for .Complete(fn)"}},
+		{"context of another backend", `package main
+
+import (
+	"github.com/tmr232/goat"
+	"github.com/tmr232/goat/stdflag"
+)
+
+func completeHosts(ctx *stdflag.Context, prefix string) []string {
+	return nil
+}
+
+func app(host string) {
+	goat.Flag(host).Complete(completeHosts)
+}
+
+func main() {
+	goat.Run(app)
+}
+`, []string{"main.go:13:27 Error: Expected a func(ctx *goat.Context, prefix string) []string for .Complete(fn)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkErrors(t, cliBackend, tt.src, tt.want...)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GoatField is an exported field of a struct parameter, which is a flag of its own.
+type GoatField struct {
+	Name string
+	Type string
+	// FlagName, Usage and Default are Go expressions, like in FlagDescription.
+	FlagName string
+	Usage    string
+	Default  string
+	Options  []string
+	// Pos is the position of the field, used for reporting name collisions.
+	Pos token.Pos
+}
+
+// kebabCase converts a Go identifier to a flag name, as in `MaxConns` to `max-conns`.
+//
+// Acronyms are kept together, so `HTTPPort` becomes `http-port`.
+func kebabCase(name string) string {
+	runes := []rune(name)
+	var words []string
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		if !unicode.IsUpper(runes[i]) {
+			continue
+		}
+		isWordStart := !unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))
+		if isWordStart {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	words = append(words, string(runes[start:]))
+	return strings.ToLower(strings.Join(words, "-"))
+}
+
+// goatTag holds the descriptors of a struct field, taken from its `goat` tag.
+type goatTag struct {
+	Name    *string
+	Usage   *string
+	Default *string
+	// Skip is true for `goat:"-"`, marking fields that are not flags.
+	Skip bool
+}
+
+// parseGoatTag parses a `goat:"name=...,usage=...,default=..."` tag.
+//
+// Values may contain commas, as long as what follows a comma doesn't look like another key.
+func parseGoatTag(tag string) (goatTag, error) {
+	if tag == "" {
+		return goatTag{}, nil
+	}
+	if tag == "-" {
+		return goatTag{Skip: true}, nil
+	}
+	var parsed goatTag
+	var current *string
+	for _, part := range strings.Split(tag, ",") {
+		key, value, found := strings.Cut(part, "=")
+		var target **string
+		switch key {
+		case "name":
+			target = &parsed.Name
+		case "usage":
+			target = &parsed.Usage
+		case "default":
+			target = &parsed.Default
+		}
+		if !found || target == nil {
+			if current == nil {
+				return goatTag{}, errors.Errorf("expected key=value, got %q", part)
+			}
+			*current += "," + part
+			continue
+		}
+		if *target != nil {
+			return goatTag{}, errors.Errorf("duplicate key %q", key)
+		}
+		*target = &value
+		current = &value
+	}
+	return parsed, nil
+}
+
+// isFlagGroup reports whether a parameter type is a struct whose fields should be expanded into flags.
+func (gh *Goatherd) isFlagGroup(typ types.Type) bool {
+	_, isNamed := typ.(*types.Named)
+	_, isStruct := typ.Underlying().(*types.Struct)
+	return isNamed && isStruct && !gh.isRegistered(typ) && !isTextType(typ)
+}
+
+// isSupportedType reports whether a type can be a flag, given the options implied by it.
+func (gh *Goatherd) isSupportedType(typ types.Type, options []string) bool {
+	return len(options) != 0 || gh.isRegistered(typ) || isTextType(typ)
+}
+
+// flagGroup expands the exported fields of a struct parameter into flags.
+//
+// The flags are named after the parameter and the field, as in `--db-host` for the `Host` field
+// of a `db` parameter, and are listed under the struct's name in the help.
+func (gh *Goatherd) flagGroup(param *types.Var) (fields []GoatField, err error) {
+	named := param.Type().(*types.Named)
+	structType := named.Underlying().(*types.Struct)
+	prefix := kebabCase(param.Name())
+	category := strconv.Quote(named.Obj().Name())
+
+	for i := 0; i < structType.NumFields(); i++ {
+		field := structType.Field(i)
+		if !field.Exported() {
+			continue
+		}
+		tag, tagErr := parseGoatTag(reflect.StructTag(structType.Tag(i)).Get("goat"))
+		if tagErr != nil {
+			gh.reportErrorAt(field.Pos(), fmt.Sprintf("Invalid goat tag for %s.%s: %s", named.Obj().Name(), field.Name(), tagErr))
+			err = errors.Wrap(tagErr, "Invalid goat tag")
+			continue
+		}
+		if tag.Skip {
+			continue
+		}
+
+		typeName := gh.typeString(field.Type())
+		options := gh.enumChoices(field.Type())
+		if !gh.isSupportedType(field.Type(), options) {
+			gh.reportErrorAt(field.Pos(), fmt.Sprintf("Unsupported flag type %s for %s.%s: no type handler, and it does not implement encoding.TextUnmarshaler or flag.Value", typeName, named.Obj().Name(), field.Name()))
+			err = errors.New("Unsupported flag type")
+			continue
+		}
+
+		name := kebabCase(field.Name())
+		if tag.Name != nil {
+			name = *tag.Name
+		}
+		usage := "\"\""
+		if tag.Usage != nil {
+			usage = strconv.Quote(*tag.Usage)
+		}
+		default_ := "nil"
+		if tag.Default != nil {
+			var defaultErr error
+			default_, defaultErr = gh.tagDefault(field, typeName, *tag.Default, options)
+			if defaultErr != nil {
+				err = defaultErr
+				continue
+			}
+		}
+		fields = append(fields, GoatField{
+			Name:     field.Name(),
+			Type:     typeName,
+			FlagName: strconv.Quote(prefix + "-" + name),
+			Usage:    usage,
+			Default:  default_,
+			Options:  append(options, "flags.Category("+category+")"),
+			Pos:      field.Pos(),
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// tagDefault converts a default value from a struct tag into an expression of the field's type.
+//
+// Values of basic types are checked and used as literals, and the rest are parsed at runtime,
+// the same way the flag parses its values.
+func (gh *Goatherd) tagDefault(field *types.Var, typeName string, value string, options []string) (string, error) {
+	basic, isBasic := field.Type().(*types.Basic)
+	if !isBasic {
+		return fmt.Sprintf("flags.MustParse[%s](%s)", typeName, strings.Join(append([]string{strconv.Quote(value)}, options...), ", ")), nil
+	}
+	if basic.Info()&types.IsString != 0 {
+		return strconv.Quote(value), nil
+	}
+	_, err := types.Eval(gh.pkg.Fset, gh.pkg.Types, token.NoPos, fmt.Sprintf("%s(%s)", basic.Name(), value))
+	if err != nil {
+		message := err.Error()
+		if typeErr, isTypeErr := err.(types.Error); isTypeErr {
+			message = typeErr.Msg
+		}
+		gh.reportErrorAt(field.Pos(), fmt.Sprintf("Invalid default value for %s field %s: %s", typeName, field.Name(), message))
+		return "", errors.Wrap(err, "Invalid default value")
+	}
+	return value, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_kebabCase(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"db", "db"},
+		{"Host", "host"},
+		{"MaxConns", "max-conns"},
+		{"HTTPPort", "http-port"},
+		{"TLS", "tls"},
+		{"httpOpts", "http-opts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := kebabCase(tt.name); got != tt.want {
+				t.Errorf("kebabCase() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseGoatTag(t *testing.T) {
+	str := func(s string) *string { return &s }
+	tests := []struct {
+		name    string
+		tag     string
+		want    goatTag
+		wantErr bool
+	}{
+		{"empty", "", goatTag{}, false},
+		{"skip", "-", goatTag{Skip: true}, false},
+		{"all keys", "name=address,usage=The host.,default=localhost", goatTag{Name: str("address"), Usage: str("The host."), Default: str("localhost")}, false},
+		{"comma in value", "usage=One, two,default=1", goatTag{Usage: str("One, two"), Default: str("1")}, false},
+		{"unknown key", "alias=a", goatTag{}, true},
+		{"duplicate key", "name=a,name=b", goatTag{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseGoatTag(tt.tag)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseGoatTag() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseGoatTag() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
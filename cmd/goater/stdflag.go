@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/tmr232/goat"
+	"github.com/tmr232/goat/stdflag"
+	"go/types"
+	"reflect"
+)
+
+// The backends are the runtimes goater generates code for.
+const (
+	cliBackend     = "cli"
+	stdflagBackend = "stdflag"
+)
+
+// runtimePaths holds the import paths of the runtime packages of the backends.
+var runtimePaths = map[string]string{
+	cliBackend:     "github.com/tmr232/goat",
+	stdflagBackend: "github.com/tmr232/goat/stdflag",
+}
+
+// parseBackend parses the command line of goater, returning the backend to generate code for.
+func parseBackend(args []string) (string, error) {
+	set := flag.NewFlagSet("goater", flag.ContinueOnError)
+	backend := set.String("backend", cliBackend, "the runtime to generate code for: cli (urfave/cli), or stdflag (the standard flag package), "+
+		"which only supports flags of basic types described by Name, Usage and Default, "+
+		"without positional arguments, flag groups or flag constraints")
+	if err := set.Parse(args); err != nil {
+		return "", err
+	}
+	if _, exists := runtimePaths[*backend]; !exists {
+		return "", errors.Errorf("unknown backend %q, expected %s or %s", *backend, cliBackend, stdflagBackend)
+	}
+	return *backend, nil
+}
+
+// contextTypes holds the Context types of the backends.
+var contextTypes = map[string]reflect.Type{
+	cliBackend:     reflect.TypeOf(goat.Context{}),
+	stdflagBackend: reflect.TypeOf(stdflag.Context{}),
+}
+
+// checkStdflagAction reports the features of an action that the stdflag backend does not support.
+//
+// The backend supports flags of basic types, described by their name, usage and default value.
+func (gh *Goatherd) checkStdflagAction(f *types.Func, action Action, constraints []FlagConstraint) (err error) {
+	for _, constraint := range constraints {
+		gh.reportError(constraint.Ids[0], fmt.Sprintf("goat.%s is not supported by the stdflag backend", constraint.Kind))
+		err = errors.New("Flag constraint used with the stdflag backend")
+	}
+	params := f.Type().(*types.Signature).Params()
+	for i, flag := range action.Flags {
+		param := params.At(i)
+		switch {
+		case flag.IsContext:
+			continue
+		case flag.IsArg:
+			gh.reportErrorAt(param.Pos(), fmt.Sprintf("%s is a positional argument, which the stdflag backend does not support", param.Name()))
+			err = errors.New("Positional argument used with the stdflag backend")
+		case len(flag.Fields) != 0:
+			gh.reportErrorAt(param.Pos(), fmt.Sprintf("%s is a group of flags, which the stdflag backend does not support", param.Name()))
+			err = errors.New("Flag group used with the stdflag backend")
+		case !stdflag.IsSupported(flag.Type):
+			gh.reportErrorAt(param.Pos(), fmt.Sprintf("Unsupported flag type %s for %s: the stdflag backend only supports basic types", flag.Type, param.Name()))
+			err = errors.New("Unsupported flag type")
+		case len(flag.Options) != 0 || len(flag.Validators) != 0:
+			gh.reportErrorAt(param.Pos(), fmt.Sprintf("%s is described by directives other than Name, Usage and Default, which the stdflag backend does not support", param.Name()))
+			err = errors.New("Unsupported directive used with the stdflag backend")
+		}
+	}
+	return err
+}
+
+// runtimeImport is the import of the runtime package in the generated code, which always names it goat.
+func runtimeImport(backend string) string {
+	return fmt.Sprintf("goat %q", runtimePaths[backend])
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseBackend(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{"default", nil, cliBackend, false},
+		{"cli", []string{"-backend=cli"}, cliBackend, false},
+		{"stdflag", []string{"-backend", "stdflag"}, stdflagBackend, false},
+		{"unknown backend", []string{"-backend=kong"}, "", true},
+		{"unknown flag", []string{"-verbose"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBackend(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseBackend() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("parseBackend() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckStdflagAction(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"basic flags", `package main
+
+import (
+	goat "github.com/tmr232/goat/stdflag"
+	"time"
+)
+
+func app(ctx *goat.Context, port int, timeout *time.Duration) {
+	goat.Flag(port).Default(8080)
+}
+
+func main() {
+	goat.Run(app)
+}
+`, nil},
+		{"unsupported features", `package main
+
+// The descriptors of the stdflag runtime don't have these features, but those of goat do.
+import (
+	"github.com/tmr232/goat"
+	std "github.com/tmr232/goat/stdflag"
+)
+
+type Options struct {
+	Verbose bool
+}
+
+func app(src string, options Options, tags []string, verbose bool, json, yaml bool) {
+	goat.Arg(src)
+	goat.Flag(verbose).Short('v')
+	goat.Exclusive(json, yaml)
+}
+
+func main() {
+	std.Run(app)
+}
+`, []string{
+			"main.go:16:17 Error: goat.Exclusive is not supported by the stdflag backend",
+			"main.go:13:10 Error: src is a positional argument, which the stdflag backend does not support",
+			"main.go:13:22 Error: options is a group of flags, which the stdflag backend does not support",
+			"main.go:13:39 Error: Unsupported flag type []string for tags: the stdflag backend only supports basic types",
+			"main.go:13:54 Error: verbose is described by directives other than Name, Usage and Default, which the stdflag backend does not support",
+		}},
+		{"context of another backend", `package main
+
+import (
+	"github.com/tmr232/goat"
+	std "github.com/tmr232/goat/stdflag"
+)
+
+func app(ctx *goat.Context) {}
+
+func main() {
+	std.Run(app)
+}
+`, []string{"main.go:8:10 Error: ctx is a context of the cli backend, but the code is generated for the stdflag backend"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkErrors(t, stdflagBackend, tt.src, tt.want...)
+		})
+	}
+}
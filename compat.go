@@ -0,0 +1,237 @@
+package goat
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"strings"
+)
+
+// SpecChange is a difference between two versions of the spec of an app.
+type SpecChange struct {
+	// Command is the full name of the command the change is in, as in `tool deploy`.
+	Command string
+	// Flag is the name of the flag the change is in, and empty for changes to the command itself.
+	Flag string
+	// Message describes the change.
+	Message string
+	// Breaking is true for changes that may break existing invocations of the app.
+	Breaking bool
+}
+
+func (c SpecChange) String() string {
+	location := c.Command
+	if c.Flag != "" {
+		location += " " + flagName(c.Flag)
+	}
+	kind := "compatible"
+	if c.Breaking {
+		kind = "breaking"
+	}
+	return fmt.Sprintf("%s: %s: %s", kind, location, c.Message)
+}
+
+// ReadSpec reads a spec written by WriteSpec, checking that it has the current SpecVersion.
+func ReadSpec(r io.Reader) (Spec, error) {
+	var spec Spec
+	if err := json.NewDecoder(r).Decode(&spec); err != nil {
+		return Spec{}, errors.Wrap(err, "failed to decode spec")
+	}
+	if spec.SpecVersion != SpecVersion {
+		return Spec{}, errors.Errorf("unsupported spec version %d, expected %d", spec.SpecVersion, SpecVersion)
+	}
+	return spec, nil
+}
+
+// CompareSpecs lists the changes between an old and a new spec of an app.
+//
+// Commands are matched by their names and aliases, and flags are matched by their names,
+// so a command or a flag that is renamed while keeping its old name as an alias is not removed.
+// Removing commands, flags or their aliases, adding required flags or positional arguments,
+// and changing the types or the defaults of flags are breaking changes.
+func CompareSpecs(oldSpec, newSpec Spec) []SpecChange {
+	var changes []SpecChange
+	compareFlags(&changes, oldSpec.Name, oldSpec.Flags, newSpec.Flags)
+	compareCommands(&changes, oldSpec.Name, oldSpec.Commands, newSpec.Commands)
+	return changes
+}
+
+// BreakingChanges returns the breaking changes out of changes.
+func BreakingChanges(changes []SpecChange) []SpecChange {
+	var breaking []SpecChange
+	for _, change := range changes {
+		if change.Breaking {
+			breaking = append(breaking, change)
+		}
+	}
+	return breaking
+}
+
+func compareCommands(changes *[]SpecChange, parent string, oldCommands, newCommands []CommandSpec) {
+	matched := make(map[string]bool)
+	for _, oldCommand := range oldCommands {
+		newCommand, found := findCommandSpec(newCommands, oldCommand.Name)
+		if !found {
+			*changes = append(*changes, SpecChange{Command: parent, Message: "command " + oldCommand.Name + " removed", Breaking: true})
+			continue
+		}
+		matched[newCommand.Name] = true
+		fullName := parent + " " + oldCommand.Name
+		if newCommand.Name != oldCommand.Name {
+			*changes = append(*changes, SpecChange{Command: fullName, Message: "renamed to " + newCommand.Name + ", keeping the old name as an alias"})
+		}
+		for _, alias := range oldCommand.Aliases {
+			if !commandHasName(newCommand, alias) {
+				*changes = append(*changes, SpecChange{Command: fullName, Message: "alias " + alias + " removed", Breaking: true})
+			}
+		}
+		if newCommand.Deprecated != "" && oldCommand.Deprecated == "" {
+			*changes = append(*changes, SpecChange{Command: fullName, Message: "deprecated: " + newCommand.Deprecated})
+		}
+		compareArgs(changes, fullName, oldCommand.Args, newCommand.Args)
+		compareFlags(changes, fullName, oldCommand.Flags, newCommand.Flags)
+		compareCommands(changes, fullName, oldCommand.Commands, newCommand.Commands)
+	}
+	for _, newCommand := range newCommands {
+		if !matched[newCommand.Name] {
+			*changes = append(*changes, SpecChange{Command: parent, Message: "command " + newCommand.Name + " added"})
+		}
+	}
+}
+
+func findCommandSpec(commands []CommandSpec, name string) (CommandSpec, bool) {
+	for _, command := range commands {
+		if commandHasName(command, name) {
+			return command, true
+		}
+	}
+	return CommandSpec{}, false
+}
+
+func commandHasName(command CommandSpec, name string) bool {
+	return command.Name == name || containsString(command.Aliases, name)
+}
+
+// compareArgs compares the positional arguments of a command by their number, as their names are not
+// a part of the command line.
+func compareArgs(changes *[]SpecChange, command string, oldArgs, newArgs []ArgSpec) {
+	oldMin, oldMax := argCounts(oldArgs)
+	newMin, newMax := argCounts(newArgs)
+	if newMin > oldMin {
+		*changes = append(*changes, SpecChange{
+			Command:  command,
+			Message:  fmt.Sprintf("required arguments increased from %d to %d", oldMin, newMin),
+			Breaking: true,
+		})
+	}
+	if oldMax < 0 && newMax >= 0 || newMax >= 0 && newMax < oldMax {
+		*changes = append(*changes, SpecChange{
+			Command:  command,
+			Message:  fmt.Sprintf("maximum arguments decreased from %s to %d", formatArgCount(oldMax), newMax),
+			Breaking: true,
+		})
+	}
+}
+
+// argCounts returns the minimal and maximal number of positional arguments, where -1 stands for any number.
+func argCounts(args []ArgSpec) (int, int) {
+	min, max := 0, 0
+	for _, arg := range args {
+		if !arg.Optional {
+			min++
+		}
+		if arg.Variadic {
+			max = -1
+		} else if max >= 0 {
+			max++
+		}
+	}
+	return min, max
+}
+
+func formatArgCount(count int) string {
+	if count < 0 {
+		return "any"
+	}
+	return fmt.Sprint(count)
+}
+
+func compareFlags(changes *[]SpecChange, command string, oldFlags, newFlags []FlagSpec) {
+	matched := make(map[string]bool)
+	for _, oldFlag := range oldFlags {
+		newFlag, found := findFlagSpec(newFlags, oldFlag.Name)
+		if !found {
+			*changes = append(*changes, SpecChange{Command: command, Flag: oldFlag.Name, Message: "removed", Breaking: true})
+			continue
+		}
+		matched[newFlag.Name] = true
+		change := func(message string, breaking bool) {
+			*changes = append(*changes, SpecChange{Command: command, Flag: oldFlag.Name, Message: message, Breaking: breaking})
+		}
+		if newFlag.Name != oldFlag.Name {
+			change("renamed to "+flagName(newFlag.Name)+", keeping the old name as an alias", false)
+		}
+		for _, alias := range oldFlag.Aliases {
+			if !flagHasName(newFlag, alias) {
+				change("alias "+flagName(alias)+" removed", true)
+			}
+		}
+		if newFlag.Type != oldFlag.Type {
+			change("type changed from "+oldFlag.Type+" to "+newFlag.Type, true)
+		}
+		if newFlag.Required && !oldFlag.Required {
+			change("became required", true)
+		} else if !newFlag.Required && oldFlag.Required {
+			change("became optional", false)
+		} else if newFlag.Type == oldFlag.Type && newFlag.Default != oldFlag.Default {
+			// A changed type is reported on its own, as the defaults of different types can't be compared.
+			change(fmt.Sprintf("default changed from %s to %s", formatDefault(oldFlag.Default), formatDefault(newFlag.Default)), true)
+		}
+		for _, envVar := range oldFlag.EnvVars {
+			if !containsString(newFlag.EnvVars, envVar) {
+				change("environment variable "+envVar+" removed", true)
+			}
+		}
+		if len(newFlag.Choices) > 0 {
+			for _, choice := range oldFlag.Choices {
+				if !containsString(newFlag.Choices, choice) {
+					change("choice "+choice+" removed", true)
+				}
+			}
+		}
+		if newFlag.Deprecated != "" && oldFlag.Deprecated == "" {
+			change("deprecated: "+newFlag.Deprecated, false)
+		}
+	}
+	for _, newFlag := range newFlags {
+		if matched[newFlag.Name] {
+			continue
+		}
+		if newFlag.Required {
+			*changes = append(*changes, SpecChange{Command: command, Flag: newFlag.Name, Message: "added as a required flag", Breaking: true})
+		} else {
+			*changes = append(*changes, SpecChange{Command: command, Flag: newFlag.Name, Message: "added"})
+		}
+	}
+}
+
+func findFlagSpec(flagSpecs []FlagSpec, name string) (FlagSpec, bool) {
+	for _, f := range flagSpecs {
+		if flagHasName(f, name) {
+			return f, true
+		}
+	}
+	return FlagSpec{}, false
+}
+
+func flagHasName(f FlagSpec, name string) bool {
+	return f.Name == name || containsString(f.Aliases, name)
+}
+
+func formatDefault(defaultText string) string {
+	if defaultText == "" {
+		return "none"
+	}
+	return strings.TrimSpace(defaultText)
+}
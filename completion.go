@@ -0,0 +1,533 @@
+package goat
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/tmr232/goat/flags"
+	"github.com/urfave/cli/v2"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// shells are the shells completion scripts are generated for.
+var shells = []string{"bash", "zsh", "fish"}
+
+// argCompletions holds the values the positional arguments of commands are completed with.
+var argCompletions = make(map[*cli.Command][]string)
+
+// CompletionCommand adds a `completion SHELL` command, printing a completion script for the app,
+// and the hidden `__complete` command the scripts call for completing flag values dynamically.
+func (app Application) CompletionCommand() Application {
+	app.Commands = append(app.Commands, completionCommand(), completeCommand())
+	return app
+}
+
+// completionCommand creates the `completion SHELL` command, printing a completion script.
+func completionCommand() *cli.Command {
+	command := &cli.Command{
+		Name:      "completion",
+		Usage:     "Print a completion script for " + strings.Join(shells[:len(shells)-1], ", ") + " or " + shells[len(shells)-1],
+		ArgsUsage: "SHELL",
+		Action: func(c *cli.Context) error {
+			if err := flags.CheckArgCount(c, 1, 1); err != nil {
+				return err
+			}
+			script, err := CompletionScript(c.App, c.Args().First())
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(c.App.Writer, script)
+			return err
+		},
+	}
+	argCompletions[command] = shells
+	return command
+}
+
+// completeCommandName is the name of the hidden command the completion scripts call
+// for completing flag values dynamically.
+const completeCommandName = "__complete"
+
+// completeCommand creates the hidden `__complete WORDS...` command, printing the candidates for completing
+// the last of the words, one per line, without running any action.
+//
+// The words are the ones following the name of the app. Only the values of flags with completion functions
+// are completed, as the rest are completed by the scripts themselves.
+func completeCommand() *cli.Command {
+	return &cli.Command{
+		Name:            completeCommandName,
+		Hidden:          true,
+		SkipFlagParsing: true,
+		HideHelp:        true,
+		Action: func(c *cli.Context) error {
+			words := c.Args().Slice()
+			if len(words) == 0 {
+				return nil
+			}
+			prefix := words[len(words)-1]
+			cliFlags := completionScope(c.App, words[:len(words)-1])
+
+			flagName, value, candidatePrefix := "", prefix, ""
+			if name, flagValue, hasValue := strings.Cut(prefix, "="); hasValue && strings.HasPrefix(name, "-") {
+				flagName, value, candidatePrefix = name, flagValue, name+"="
+			} else if len(words) > 1 {
+				flagName = words[len(words)-2]
+			}
+			if !strings.HasPrefix(flagName, "-") {
+				return nil
+			}
+			complete := flags.GetCompleteFunc(findFlag(cliFlags, strings.TrimLeft(flagName, "-")))
+			if complete == nil {
+				return nil
+			}
+			for _, candidate := range complete(c, value) {
+				if strings.HasPrefix(candidate, value) {
+					fmt.Fprintln(c.App.Writer, candidatePrefix+candidate)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// completionScope returns the flags of the app and of the commands named in the words.
+func completionScope(app *cli.App, words []string) []cli.Flag {
+	cliFlags := append([]cli.Flag(nil), app.Flags...)
+	commands := app.Commands
+	for _, word := range words {
+		if word == "--" {
+			break
+		}
+		for _, command := range commands {
+			if command.HasName(word) {
+				cliFlags = append(cliFlags, command.Flags...)
+				commands = command.Subcommands
+				break
+			}
+		}
+	}
+	return cliFlags
+}
+
+// findFlag finds a flag by any of its names. Later flags take precedence, so that the flags of
+// subcommands hide the flags of their parents.
+func findFlag(cliFlags []cli.Flag, name string) cli.Flag {
+	for i := len(cliFlags) - 1; i >= 0; i-- {
+		for _, flagName := range cliFlags[i].Names() {
+			if flagName == name {
+				return cliFlags[i]
+			}
+		}
+	}
+	return nil
+}
+
+// CompleteFunc adapts a function completing the values of a flag to the flags package.
+//
+// This is only used in generated code.
+func CompleteFunc(complete func(ctx *Context, prefix string) []string) flags.CompleteFunc {
+	return func(c *cli.Context, prefix string) []string {
+		return complete(GetContext(c), prefix)
+	}
+}
+
+// CompletionScript generates a script completing the commands and flags of an app, for bash, zsh or fish.
+//
+// Subcommand names, flag names and enum values are completed, as well as file paths for the flags taking them.
+// Flags with completion functions are completed by calling the app. Positional arguments are completed with file paths.
+func CompletionScript(app *cli.App, shell string) (string, error) {
+	root := &completionNode{
+		path:     app.Name,
+		flags:    completionFlags(app.Flags, app.HideHelp),
+		commands: completionNodes(app.Name, app.VisibleCommands()),
+	}
+	switch shell {
+	case "bash":
+		return bashCompletion(root), nil
+	case "zsh":
+		return zshCompletion(root), nil
+	case "fish":
+		return fishCompletion(root), nil
+	}
+	return "", errors.Errorf("unsupported shell %q, expected one of: %s", shell, strings.Join(shells, ", "))
+}
+
+// completionNode describes a command for shell completion.
+type completionNode struct {
+	// path is the name of the app, followed by the names of the commands leading to the command.
+	path     string
+	names    []string
+	usage    string
+	commands []*completionNode
+	flags    []completionFlag
+	// args are the values positional arguments are completed with. If there are none, file paths are used.
+	args []string
+}
+
+type completionFlag struct {
+	// names are the names of the flag, with their dashes.
+	names      []string
+	usage      string
+	takesValue bool
+	takesFile  bool
+	choices    []string
+	// dynamic flags are completed by calling the app, using the hidden __complete command.
+	dynamic bool
+}
+
+func completionNodes(parentPath string, commands []*cli.Command) []*completionNode {
+	nodes := make([]*completionNode, len(commands))
+	for i, command := range commands {
+		path := parentPath + " " + command.Name
+		nodes[i] = &completionNode{
+			path:     path,
+			names:    command.Names(),
+			usage:    firstLine(command.Usage),
+			flags:    completionFlags(command.Flags, command.HideHelp),
+			commands: completionNodes(path, command.VisibleCommands()),
+			args:     argCompletions[command],
+		}
+	}
+	return nodes
+}
+
+// completionFlags describes the visible flags, along with the help flag cli adds to commands when they run.
+func completionFlags(cliFlags []cli.Flag, hideHelp bool) []completionFlag {
+	if !hideHelp && cli.HelpFlag != nil && !hasFlagNamed(cliFlags, cli.HelpFlag.Names()[0]) {
+		cliFlags = append(cliFlags[:len(cliFlags):len(cliFlags)], cli.HelpFlag)
+	}
+	var completions []completionFlag
+	for _, f := range cliFlags {
+		if visibleFlag, isVisibleFlag := f.(cli.VisibleFlag); !isVisibleFlag || !visibleFlag.IsVisible() {
+			continue
+		}
+		completion := completionFlag{
+			takesFile: flags.TakesFile(f),
+			choices:   flags.Choices(f),
+			dynamic:   flags.GetCompleteFunc(f) != nil,
+		}
+		for _, name := range f.Names() {
+			if len(name) == 1 {
+				completion.names = append(completion.names, "-"+name)
+			} else {
+				completion.names = append(completion.names, "--"+name)
+			}
+		}
+		if docFlag, isDocFlag := f.(cli.DocGenerationFlag); isDocFlag {
+			// The usage may name the value using backquotes, which are only meaningful to the help.
+			completion.usage = firstLine(strings.ReplaceAll(docFlag.GetUsage(), "`", ""))
+			completion.takesValue = docFlag.TakesValue()
+		}
+		completions = append(completions, completion)
+	}
+	return completions
+}
+
+func hasFlagNamed(cliFlags []cli.Flag, name string) bool {
+	for _, f := range cliFlags {
+		for _, flagName := range f.Names() {
+			if flagName == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return line
+}
+
+// walk calls f for the node and all the commands under it, parents first.
+func (node *completionNode) walk(f func(node *completionNode)) {
+	f(node)
+	for _, command := range node.commands {
+		command.walk(f)
+	}
+}
+
+// commandNames returns the names of the subcommands of the node.
+func (node *completionNode) commandNames() []string {
+	var names []string
+	for _, command := range node.commands {
+		names = append(names, command.names...)
+	}
+	return names
+}
+
+func (node *completionNode) flagNames() []string {
+	var names []string
+	for _, f := range node.flags {
+		names = append(names, f.names...)
+	}
+	return names
+}
+
+var nonIdentifierChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// functionName derives the name of a shell function from the name of the app.
+func functionName(format string, appName string) string {
+	return fmt.Sprintf(format, nonIdentifierChars.ReplaceAllString(appName, "_"))
+}
+
+// shellQuote quotes a string using single quotes, for bash and zsh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote quotes a string using single quotes, for fish.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// commandTransitions writes the cases of a `case` statement, tracking the command
+// being completed by matching "$command/$word" patterns.
+func commandTransitions(b *strings.Builder, root *completionNode, indent string, assign func(path string) string) {
+	root.walk(func(node *completionNode) {
+		for _, command := range node.commands {
+			patterns := make([]string, len(command.names))
+			for i, name := range command.names {
+				patterns[i] = shellQuote(node.path + "/" + name)
+			}
+			fmt.Fprintf(b, "%s%s) %s ;;\n", indent, strings.Join(patterns, "|"), assign(command.path))
+		}
+	})
+}
+
+// valueCompletions writes the cases of a `case` statement matching "$command/$previous",
+// completing the values of flags.
+func valueCompletions(b *strings.Builder, root *completionNode, indent string, choices func([]string) string, files string, dynamic string) {
+	root.walk(func(node *completionNode) {
+		for _, f := range node.flags {
+			if !f.takesValue {
+				continue
+			}
+			patterns := make([]string, len(f.names))
+			for i, name := range f.names {
+				patterns[i] = shellQuote(node.path + "/" + name)
+			}
+			completion := ""
+			switch {
+			case f.dynamic:
+				completion = dynamic + "; "
+			case len(f.choices) != 0:
+				completion = choices(f.choices) + "; "
+			case f.takesFile:
+				completion = files + "; "
+			}
+			fmt.Fprintf(b, "%s%s) %sreturn ;;\n", indent, strings.Join(patterns, "|"), completion)
+		}
+	})
+}
+
+func bashCompletion(root *completionNode) string {
+	function := functionName("_%s_completion", root.path)
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "# bash completion for %s\n", root.path)
+	fmt.Fprintf(b, "# Load it using: source <(%s completion bash)\n\n", root.path)
+	fmt.Fprintf(b, "%s() {\n", function)
+	b.WriteString("    local cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	fmt.Fprintf(b, "    local command=%s i\n", shellQuote(root.path))
+	b.WriteString("    for ((i = 1; i < COMP_CWORD; i++)); do\n")
+	b.WriteString("        case \"$command/${COMP_WORDS[i]}\" in\n")
+	commandTransitions(b, root, "            ", func(path string) string {
+		return "command=" + shellQuote(path)
+	})
+	b.WriteString("        esac\n")
+	b.WriteString("    done\n\n")
+
+	b.WriteString("    case \"$command/$prev\" in\n")
+	valueCompletions(b, root, "        ", func(choices []string) string {
+		return "COMPREPLY=($(compgen -W " + shellQuote(strings.Join(choices, " ")) + " -- \"$cur\"))"
+	}, "COMPREPLY=($(compgen -f -- \"$cur\"))",
+		"COMPREPLY=($(compgen -W \"$(\"${COMP_WORDS[0]}\" "+completeCommandName+" \"${COMP_WORDS[@]:1:COMP_CWORD}\")\" -- \"$cur\"))")
+	b.WriteString("    esac\n\n")
+
+	b.WriteString("    local commands='' flags='' args=''\n")
+	b.WriteString("    case \"$command\" in\n")
+	root.walk(func(node *completionNode) {
+		fmt.Fprintf(b, "        %s)\n", shellQuote(node.path))
+		if names := node.commandNames(); len(names) != 0 {
+			fmt.Fprintf(b, "            commands=%s\n", shellQuote(strings.Join(names, " ")))
+		}
+		if len(node.args) != 0 {
+			fmt.Fprintf(b, "            args=%s\n", shellQuote(strings.Join(node.args, " ")))
+		}
+		fmt.Fprintf(b, "            flags=%s\n", shellQuote(strings.Join(node.flagNames(), " ")))
+		b.WriteString("            ;;\n")
+	})
+	b.WriteString("    esac\n")
+	b.WriteString("    if [[ \"$cur\" == -* ]]; then\n")
+	b.WriteString("        COMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n")
+	b.WriteString("    else\n")
+	b.WriteString("        COMPREPLY=($(compgen -W \"$commands $args\" -- \"$cur\"))\n")
+	b.WriteString("    fi\n")
+	b.WriteString("}\n\n")
+	fmt.Fprintf(b, "complete -o default -F %s %s\n", function, root.path)
+	return b.String()
+}
+
+// zshDescribed formats an item for _describe, escaping the colons in the name.
+func zshDescribed(name string, usage string) string {
+	name = strings.ReplaceAll(name, ":", `\:`)
+	if usage == "" {
+		return shellQuote(name)
+	}
+	return shellQuote(name + ":" + usage)
+}
+
+func zshCompletion(root *completionNode) string {
+	function := functionName("_%s", root.path)
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "#compdef %s\n", root.path)
+	fmt.Fprintf(b, "# zsh completion for %s\n", root.path)
+	fmt.Fprintf(b, "# Load it using: source <(%s completion zsh)\n\n", root.path)
+	fmt.Fprintf(b, "%s() {\n", function)
+	fmt.Fprintf(b, "    local command=%s i\n", shellQuote(root.path))
+	b.WriteString("    for ((i = 2; i < CURRENT; i++)); do\n")
+	b.WriteString("        case \"$command/${words[i]}\" in\n")
+	commandTransitions(b, root, "            ", func(path string) string {
+		return "command=" + shellQuote(path)
+	})
+	b.WriteString("        esac\n")
+	b.WriteString("    done\n\n")
+
+	b.WriteString("    case \"$command/${words[CURRENT-1]}\" in\n")
+	valueCompletions(b, root, "        ", func(choices []string) string {
+		quoted := make([]string, len(choices))
+		for i, choice := range choices {
+			quoted[i] = shellQuote(choice)
+		}
+		return "compadd -- " + strings.Join(quoted, " ")
+	}, "_files", "compadd -- ${(f)\"$(${words[1]} "+completeCommandName+" ${words[2,CURRENT]})\"}")
+	b.WriteString("    esac\n\n")
+
+	b.WriteString("    local -a commands flags args\n")
+	b.WriteString("    case \"$command\" in\n")
+	root.walk(func(node *completionNode) {
+		fmt.Fprintf(b, "        %s)\n", shellQuote(node.path))
+		if len(node.commands) != 0 {
+			var items []string
+			for _, command := range node.commands {
+				for _, name := range command.names {
+					items = append(items, zshDescribed(name, command.usage))
+				}
+			}
+			fmt.Fprintf(b, "            commands=(%s)\n", strings.Join(items, " "))
+		}
+		if len(node.args) != 0 {
+			quoted := make([]string, len(node.args))
+			for i, arg := range node.args {
+				quoted[i] = shellQuote(arg)
+			}
+			fmt.Fprintf(b, "            args=(%s)\n", strings.Join(quoted, " "))
+		}
+		var items []string
+		for _, f := range node.flags {
+			for _, name := range f.names {
+				items = append(items, zshDescribed(name, f.usage))
+			}
+		}
+		fmt.Fprintf(b, "            flags=(%s)\n", strings.Join(items, " "))
+		b.WriteString("            ;;\n")
+	})
+	b.WriteString("    esac\n")
+	b.WriteString("    if [[ \"${words[CURRENT]}\" == -* ]]; then\n")
+	b.WriteString("        _describe -t flags 'flag' flags\n")
+	b.WriteString("    elif (( ${#commands} )); then\n")
+	b.WriteString("        _describe -t commands 'command' commands\n")
+	b.WriteString("    elif (( ${#args} )); then\n")
+	b.WriteString("        compadd -- \"${args[@]}\"\n")
+	b.WriteString("    else\n")
+	b.WriteString("        _files\n")
+	b.WriteString("    fi\n")
+	b.WriteString("}\n\n")
+	fmt.Fprintf(b, "if [ \"$funcstack[1]\" = %s ]; then\n", shellQuote(function))
+	fmt.Fprintf(b, "    %s \"$@\"\n", function)
+	b.WriteString("else\n")
+	fmt.Fprintf(b, "    compdef %s %s\n", function, root.path)
+	b.WriteString("fi\n")
+	return b.String()
+}
+
+func fishCompletion(root *completionNode) string {
+	commandFunction := functionName("__%s_command", root.path)
+	usingFunction := functionName("__%s_using", root.path)
+	completeFunction := functionName("__%s_complete", root.path)
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "# fish completion for %s\n", root.path)
+	fmt.Fprintf(b, "# Load it using: %s completion fish | source\n\n", root.path)
+	fmt.Fprintf(b, "function %s\n", commandFunction)
+	b.WriteString("    set -l words (commandline -opc)\n")
+	b.WriteString("    set -e words[1]\n")
+	fmt.Fprintf(b, "    set -l command %s\n", fishQuote(root.path))
+	b.WriteString("    for word in $words\n")
+	b.WriteString("        switch \"$command/$word\"\n")
+	root.walk(func(node *completionNode) {
+		for _, command := range node.commands {
+			patterns := make([]string, len(command.names))
+			for i, name := range command.names {
+				patterns[i] = fishQuote(node.path + "/" + name)
+			}
+			fmt.Fprintf(b, "            case %s\n", strings.Join(patterns, " "))
+			fmt.Fprintf(b, "                set command %s\n", fishQuote(command.path))
+		}
+	})
+	b.WriteString("        end\n")
+	b.WriteString("    end\n")
+	b.WriteString("    echo $command\n")
+	b.WriteString("end\n\n")
+	fmt.Fprintf(b, "function %s\n", usingFunction)
+	fmt.Fprintf(b, "    test (%s) = \"$argv[1]\"\n", commandFunction)
+	b.WriteString("end\n\n")
+	fmt.Fprintf(b, "function %s\n", completeFunction)
+	b.WriteString("    set -l words (commandline -opc)\n")
+	b.WriteString("    set -l command $words[1]\n")
+	b.WriteString("    set -e words[1]\n")
+	fmt.Fprintf(b, "    $command %s $words (commandline -ct)\n", completeCommandName)
+	b.WriteString("end\n\n")
+
+	root.walk(func(node *completionNode) {
+		prefix := fmt.Sprintf("complete -c %s -n \"%s %s\"", root.path, usingFunction, fishQuote(node.path))
+		for _, command := range node.commands {
+			for _, name := range command.names {
+				line := prefix + " -f -a " + fishQuote(name)
+				if command.usage != "" {
+					line += " -d " + fishQuote(command.usage)
+				}
+				b.WriteString(line + "\n")
+			}
+		}
+		if len(node.args) != 0 {
+			fmt.Fprintf(b, "%s -f -a %s\n", prefix, fishQuote(strings.Join(node.args, " ")))
+		}
+		for _, f := range node.flags {
+			line := prefix
+			for _, name := range f.names {
+				if strings.HasPrefix(name, "--") {
+					line += " -l " + strings.TrimPrefix(name, "--")
+				} else {
+					line += " -s " + strings.TrimPrefix(name, "-")
+				}
+			}
+			switch {
+			case !f.takesValue:
+			case f.dynamic:
+				line += " -x -a " + fishQuote("("+completeFunction+")")
+			case len(f.choices) != 0:
+				line += " -x -a " + fishQuote(strings.Join(f.choices, " "))
+			case f.takesFile:
+				line += " -r -F"
+			default:
+				line += " -x"
+			}
+			if f.usage != "" {
+				line += " -d " + fishQuote(f.usage)
+			}
+			b.WriteString(line + "\n")
+		}
+	})
+	return b.String()
+}
@@ -0,0 +1,249 @@
+package goat
+
+import (
+	"github.com/pkg/errors"
+	"github.com/tmr232/goat/flags"
+	"github.com/urfave/cli/v2"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DocPage is a documentation page of an app or one of its commands.
+type DocPage struct {
+	// Name is the file name of the page, as in `tool-deploy.1` or `tool-deploy.md`.
+	Name    string
+	Content string
+}
+
+// writeDocPages writes pages to a directory, creating it if needed.
+func writeDocPages(pages []DocPage, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return errors.Wrap(err, "failed to create docs directory")
+	}
+	for _, page := range pages {
+		if err := os.WriteFile(filepath.Join(dir, page.Name), []byte(page.Content), 0o644); err != nil {
+			return errors.Wrap(err, "failed to write docs page")
+		}
+	}
+	return nil
+}
+
+// commandDoc describes a command, for generating documentation.
+type commandDoc struct {
+	// path holds the name of the app, followed by the names of the commands leading to the command.
+	path []string
+	// usage is the full usage of the command, which is the doc comment for goater-documented functions.
+	usage      string
+	aliases    []string
+	argsUsage  string
+	args       []flags.Arg
+	flags      []flagDoc
+	commands   []*commandDoc
+	category   string
+	deprecated string
+	hidden     bool
+	// isGroup is true for commands created using Group, which only hold subcommands.
+	isGroup bool
+}
+
+// flagDoc describes a flag, for generating documentation.
+type flagDoc struct {
+	names []string
+	// typ is the Go type of the flag.
+	typ   string
+	usage string
+	// placeholder names the value of flags taking values, as in `--config FILE`.
+	placeholder string
+	takesValue  bool
+	defaultText string
+	envVars     []string
+	required    bool
+	category    string
+	hidden      bool
+	deprecated  string
+	choices     []string
+}
+
+func appDoc(app *cli.App) *commandDoc {
+	return &commandDoc{
+		path:      []string{app.Name},
+		usage:     app.Usage,
+		argsUsage: app.ArgsUsage,
+		flags:     flagDocs(app.Flags),
+		commands:  commandDocs([]string{app.Name}, app.Commands),
+	}
+}
+
+func commandDocs(parentPath []string, commands []*cli.Command) []*commandDoc {
+	var docs []*commandDoc
+	for _, command := range commands {
+		// cli adds the help command when the app runs.
+		if command.Name == "help" {
+			continue
+		}
+		path := append(parentPath[:len(parentPath):len(parentPath)], command.Name)
+		doc := &commandDoc{
+			path:      path,
+			usage:     command.Usage,
+			aliases:   command.Aliases,
+			argsUsage: command.ArgsUsage,
+			flags:     flagDocs(command.Flags),
+			commands:  commandDocs(path, command.Subcommands),
+			category:  command.Category,
+			hidden:    command.Hidden,
+			isGroup:   command.Action == nil,
+		}
+		if config, isGoatCommand := configByCommand[command]; isGoatCommand {
+			// The usage of the command notes the deprecation, which is described separately.
+			doc.usage = config.Usage
+			doc.args = config.Args
+			doc.deprecated = config.Deprecated
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+var placeholderPattern = regexp.MustCompile("`([^`]*)`")
+
+func flagDocs(cliFlags []cli.Flag) []flagDoc {
+	var docs []flagDoc
+	for _, f := range cliFlags {
+		// The flag applying config files has no names, and the help flag is added by cli.
+		if len(f.Names()) == 0 || f == cli.HelpFlag {
+			continue
+		}
+		doc := flagDoc{
+			names:      f.Names(),
+			typ:        flags.TypeName(f),
+			deprecated: flags.DeprecationMessage(f),
+			choices:    flags.Choices(f),
+		}
+		if docFlag, isDocFlag := f.(cli.DocGenerationFlag); isDocFlag {
+			doc.usage = docFlag.GetUsage()
+			doc.takesValue = docFlag.TakesValue()
+			doc.envVars = docFlag.GetEnvVars()
+			if doc.takesValue {
+				doc.defaultText = docFlag.GetDefaultText()
+			}
+		}
+		if doc.takesValue {
+			doc.placeholder = "value"
+			if match := placeholderPattern.FindStringSubmatch(doc.usage); match != nil {
+				doc.placeholder = match[1]
+			}
+		}
+		doc.usage = strings.ReplaceAll(doc.usage, "`", "")
+		if requiredFlag, isRequiredFlag := f.(cli.RequiredFlag); isRequiredFlag {
+			doc.required = requiredFlag.IsRequired()
+		}
+		if categorizable, isCategorizable := f.(cli.CategorizableFlag); isCategorizable {
+			doc.category = categorizable.GetCategory()
+		}
+		if visibleFlag, isVisibleFlag := f.(cli.VisibleFlag); isVisibleFlag {
+			doc.hidden = !visibleFlag.IsVisible()
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+// pageName is the name of the pages of a command, without an extension, as in `tool-db-migrate`.
+func (doc *commandDoc) pageName() string {
+	return strings.Join(doc.path, "-")
+}
+
+// name is the full name of the command, as in `tool db migrate`.
+func (doc *commandDoc) name() string {
+	return strings.Join(doc.path, " ")
+}
+
+// summary is the first paragraph of the usage, in a single line.
+func (doc *commandDoc) summary() string {
+	paragraph, _, _ := strings.Cut(strings.TrimSpace(doc.usage), "\n\n")
+	return strings.Join(strings.Fields(paragraph), " ")
+}
+
+// paragraphs splits the usage into paragraphs.
+func (doc *commandDoc) paragraphs() []string {
+	var paragraphs []string
+	for _, paragraph := range strings.Split(strings.TrimSpace(doc.usage), "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			paragraphs = append(paragraphs, paragraph)
+		}
+	}
+	return paragraphs
+}
+
+// visibleCommands returns the subcommands that are not hidden.
+func (doc *commandDoc) visibleCommands() []*commandDoc {
+	var commands []*commandDoc
+	for _, command := range doc.commands {
+		if !command.hidden {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+// visibleFlags returns the flags that are not hidden, with the uncategorized flags first,
+// and the rest grouped by their categories, in the order the categories first appear in.
+func (doc *commandDoc) visibleFlags() []flagDoc {
+	var visible []flagDoc
+	var categories []string
+	for _, f := range doc.flags {
+		if f.hidden {
+			continue
+		}
+		if f.category == "" {
+			visible = append(visible, f)
+		} else if !containsString(categories, f.category) {
+			categories = append(categories, f.category)
+		}
+	}
+	for _, category := range categories {
+		for _, f := range doc.flags {
+			if !f.hidden && f.category == category {
+				visible = append(visible, f)
+			}
+		}
+	}
+	return visible
+}
+
+// describedArgs returns the positional arguments if any of them has a usage or a default value.
+func (doc *commandDoc) describedArgs() []flags.Arg {
+	for _, arg := range doc.args {
+		if arg.Usage != "" || arg.DefaultText != "" {
+			return doc.args
+		}
+	}
+	return nil
+}
+
+// walk calls f for the command and all the visible commands under it, parents first.
+func (doc *commandDoc) walk(f func(doc *commandDoc)) {
+	f(doc)
+	for _, command := range doc.visibleCommands() {
+		command.walk(f)
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// flagName formats a flag name with its dashes, as in `--name` or `-n`.
+func flagName(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
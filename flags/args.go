@@ -0,0 +1,148 @@
+package flags
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+	"io"
+	"reflect"
+)
+
+// Arg describes a positional argument, for help texts and documentation.
+type Arg struct {
+	Name  string
+	Usage string
+	// DefaultText is the formatted default value of the argument, if it has one.
+	DefaultText string
+	// Optional arguments may be omitted, and always come after the required ones.
+	Optional bool
+	// Variadic arguments take all the remaining positional arguments, and always come last.
+	Variadic bool
+}
+
+// MakeArg describes a positional argument from a type and description values.
+//
+// Like flags, arguments are optional if T is a pointer type or if they have a default value.
+func MakeArg[T any](name string, usage string, defaultValue any, options ...Option) Arg {
+	arg := Arg{
+		Name:     name,
+		Usage:    usage,
+		Optional: defaultValue != nil || reflect.TypeOf(*new(T)).Kind() == reflect.Pointer,
+	}
+	// The flag's usage and default value are formatted the same way for arguments.
+	if docFlag, isDocFlag := MakeFlag[T](name, usage, defaultValue, options...).(cli.DocGenerationFlag); isDocFlag {
+		arg.Usage = docFlag.GetUsage()
+		if defaultValue != nil {
+			arg.DefaultText = docFlag.GetDefaultText()
+		}
+	}
+	return arg
+}
+
+// MakeVariadicArg describes a variadic positional argument, taking all the remaining
+// positional arguments as values of type T.
+func MakeVariadicArg[T any](name string, usage string, options ...Option) Arg {
+	arg := Arg{
+		Name:     name,
+		Usage:    usage,
+		Optional: true,
+		Variadic: true,
+	}
+	if docFlag, isDocFlag := MakeFlag[T](name, usage, nil, options...).(cli.DocGenerationFlag); isDocFlag {
+		arg.Usage = docFlag.GetUsage()
+	}
+	return arg
+}
+
+// usageError reports an error in the positional arguments or in the combination of flags
+// along with the help text,
+// the same way urfave/cli reports errors in flags.
+func usageError(c *cli.Context, err error) error {
+	_, _ = fmt.Fprintf(c.App.Writer, "Incorrect Usage: %s\n\n", err)
+	if c.Command == nil || c.Command.Name == "" {
+		// The root context has an empty command.
+		_ = cli.ShowAppHelp(c)
+	} else {
+		_ = cli.ShowCommandHelp(c, c.Command.Name)
+	}
+	return err
+}
+
+func pluralArguments(count int) string {
+	if count == 1 {
+		return "1 argument"
+	}
+	return fmt.Sprintf("%d arguments", count)
+}
+
+// CheckArgCount makes sure that the number of positional arguments is between min and max.
+//
+// A negative max means there is no upper limit, as there is a variadic argument.
+func CheckArgCount(c *cli.Context, min int, max int) error {
+	count := c.NArg()
+	switch {
+	case min == max && count != min:
+		return usageError(c, errors.Errorf("expected %s, got %d", pluralArguments(min), count))
+	case count < min:
+		return usageError(c, errors.Errorf("expected at least %s, got %d", pluralArguments(min), count))
+	case max >= 0 && count > max:
+		return usageError(c, errors.Errorf("expected at most %s, got %d", pluralArguments(max), count))
+	}
+	return nil
+}
+
+// parseArg parses the value of a positional argument the same way as a flag of type T
+// with the same description.
+//
+// If value is nil, the argument holds its default value.
+func parseArg[T any](name string, value *string, defaultValue any, options ...Option) (T, error) {
+	set := flag.NewFlagSet(name, flag.ContinueOnError)
+	set.SetOutput(io.Discard)
+	err := MakeFlag[T](name, "", defaultValue, options...).Apply(set)
+	if err != nil {
+		return *new(T), errors.Wrapf(err, "failed creating argument %s", name)
+	}
+	if value != nil {
+		err = set.Set(name, *value)
+		if err != nil {
+			return *new(T), err
+		}
+	}
+	return GetFlag[T](cli.NewContext(nil, set, nil), name), nil
+}
+
+// GetArg gets the value of the positional argument at index.
+//
+// The argument is parsed the same way as a flag of type T with the same description,
+// and holds its default value if it was not provided.
+func GetArg[T any](c *cli.Context, index int, name string, defaultValue any, options ...Option) (T, error) {
+	var value *string
+	if index < c.NArg() {
+		arg := c.Args().Get(index)
+		value = &arg
+	}
+	parsed, err := parseArg[T](name, value, defaultValue, options...)
+	if err != nil && value == nil {
+		return *new(T), err
+	}
+	if err != nil {
+		return *new(T), usageError(c, errors.Wrapf(err, "invalid value %q for argument %s", *value, name))
+	}
+	return parsed, nil
+}
+
+// GetVariadicArg gets the values of all the positional arguments from index onwards,
+// parsing each of them as a T.
+func GetVariadicArg[T any](c *cli.Context, index int, name string, options ...Option) ([]T, error) {
+	var values []T
+	for i := index; i < c.NArg(); i++ {
+		arg := c.Args().Get(i)
+		value, err := parseArg[T](name, &arg, nil, options...)
+		if err != nil {
+			return nil, usageError(c, errors.Wrapf(err, "invalid value %q for argument %s[%d]", arg, name, i-index))
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
@@ -0,0 +1,36 @@
+package flags
+
+import (
+	"github.com/urfave/cli/v2"
+	"reflect"
+)
+
+// CompleteFunc returns the candidates for completing the value of a flag, given the text typed so far.
+type CompleteFunc func(c *cli.Context, prefix string) []string
+
+// Complete sets the function completing the values of a flag, when asked by the shell.
+func Complete(complete CompleteFunc) Option {
+	return func(desc *Description) {
+		desc.Complete = complete
+	}
+}
+
+// GetCompleteFunc returns the function completing the values of a flag, or nil if it has none.
+func GetCompleteFunc(f cli.Flag) CompleteFunc {
+	return lookupInfo(f).complete
+}
+
+// Choices returns the values accepted by an enum flag, or nil for other flags.
+func Choices(f cli.Flag) []string {
+	return lookupInfo(f).choices
+}
+
+// TakesFile is true for flags taking file paths, marked using File or cli's TakesFile field.
+func TakesFile(f cli.Flag) bool {
+	flagValue := reflect.ValueOf(f)
+	if flagValue.Kind() != reflect.Pointer || flagValue.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	takesFile := flagValue.Elem().FieldByName("TakesFile")
+	return takesFile.Kind() == reflect.Bool && takesFile.Bool()
+}
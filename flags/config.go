@@ -0,0 +1,534 @@
+package flags
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConfigFile holds flag values read from a configuration file.
+//
+// The keys of the file mirror the command tree: the values of the app's flags are at
+// the top level, and the values of a command's flags are nested under the command's name.
+// JSON (.json), YAML (.yaml, .yml) and TOML (.toml) files are supported.
+type ConfigFile struct {
+	defaultPath string
+	path        string
+	values      map[string]any
+	// passedPath is the path passed using the flag created by PathFlag, or empty.
+	passedPath string
+	isLoaded   bool
+	// top is the flag applying the values at the top level of the file, once it is applied.
+	top *configFlag
+}
+
+// NewConfigFile creates a ConfigFile, read from defaultPath unless another path is given to Load.
+func NewConfigFile(defaultPath string) *ConfigFile {
+	return &ConfigFile{defaultPath: defaultPath, path: defaultPath}
+}
+
+// Load reads the file, replacing the values read before.
+//
+// If path is empty, the default path is used, and it is not an error for the file to be missing.
+// A leading `~/` stands for the home directory.
+func (cf *ConfigFile) Load(path string) error {
+	mustExist := path != ""
+	if path == "" {
+		path = cf.defaultPath
+	}
+	cf.path, cf.values, cf.isLoaded = path, nil, true
+
+	data, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		if os.IsNotExist(err) && !mustExist {
+			return nil
+		}
+		return errors.Wrap(err, "failed to read config file")
+	}
+	values, err := decodeConfig(path, data)
+	if err != nil {
+		return errors.Wrapf(err, "%s", path)
+	}
+	cf.values = values
+	return nil
+}
+
+// load reads the file passed using the path flag, or the default file if no file was passed,
+// unless it was read already.
+func (cf *ConfigFile) load() error {
+	if cf.isLoaded && (cf.path == cf.passedPath || cf.passedPath == "") {
+		return nil
+	}
+	return cf.Load(cf.passedPath)
+}
+
+// PathFlag creates a flag giving the path of the file, read from the default path if the flag is not passed.
+//
+// The path is known only after the flags of the app are parsed, so the values at the top level of the file
+// are applied again once it is passed, and the flags of the commands are only applied the values of that file.
+func (cf *ConfigFile) PathFlag(name string, usage string) cli.Flag {
+	return &configPathFlag{
+		StringFlag: &cli.StringFlag{
+			Name:      name,
+			Usage:     usage,
+			Value:     cf.defaultPath,
+			TakesFile: true,
+		},
+		file: cf,
+	}
+}
+
+// configPathFlag is a cli.StringFlag loading the config file when it is set.
+type configPathFlag struct {
+	*cli.StringFlag
+	file *ConfigFile
+}
+
+func (f *configPathFlag) Apply(set *flag.FlagSet) error {
+	if err := f.StringFlag.Apply(set); err != nil {
+		return err
+	}
+	for _, name := range f.Names() {
+		if target := set.Lookup(name); target != nil {
+			target.Value = &configPathValue{Value: target.Value, file: f.file}
+		}
+	}
+	if f.HasBeenSet {
+		// Set by an environment variable.
+		return f.file.setPassedPath(set.Lookup(f.Name).Value.String())
+	}
+	return nil
+}
+
+// configPathValue wraps the value of a configPathFlag, loading the file when the flag is passed.
+type configPathValue struct {
+	flag.Value
+	file *ConfigFile
+}
+
+func (v *configPathValue) Set(path string) error {
+	if err := v.Value.Set(path); err != nil {
+		return err
+	}
+	return v.file.setPassedPath(path)
+}
+
+// setPassedPath reads the file passed using the path flag, and applies its values to the flags of the app.
+func (cf *ConfigFile) setPassedPath(path string) error {
+	cf.passedPath = path
+	if err := cf.load(); err != nil {
+		return err
+	}
+	if cf.top == nil {
+		return nil
+	}
+	return cf.top.reapply()
+}
+
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
+
+func configFormat(path string) string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+// decodeConfig decodes a config file into nested maps and slices of strings,
+// so that every value is parsed by its flag, the same way as on the command line.
+func decodeConfig(path string, data []byte) (map[string]any, error) {
+	var decoded any
+	switch configFormat(path) {
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var values map[string]any
+		if err := decoder.Decode(&values); err != nil {
+			return nil, err
+		}
+		decoded = normalizeConfigValue(values)
+	case "yaml", "yml":
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return nil, err
+		}
+		decoded = yamlNodeValue(&node)
+	case "toml":
+		var values map[string]any
+		if _, err := toml.Decode(string(data), &values); err != nil {
+			return nil, err
+		}
+		decoded = normalizeConfigValue(values)
+	default:
+		return nil, errors.Errorf("unsupported config file format %q", filepath.Ext(path))
+	}
+	if decoded == nil {
+		return nil, nil
+	}
+	values, isTable := decoded.(map[string]any)
+	if !isTable {
+		return nil, errors.New("expected a table at the top level")
+	}
+	return values, nil
+}
+
+// yamlNodeValue converts a YAML node, keeping scalars as they are written in the file.
+func yamlNodeValue(node *yaml.Node) any {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return yamlNodeValue(node.Content[0])
+	case yaml.AliasNode:
+		return yamlNodeValue(node.Alias)
+	case yaml.MappingNode:
+		values := make(map[string]any)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if value := yamlNodeValue(node.Content[i+1]); value != nil {
+				values[node.Content[i].Value] = value
+			}
+		}
+		return values
+	case yaml.SequenceNode:
+		values := make([]any, 0, len(node.Content))
+		for _, item := range node.Content {
+			values = append(values, yamlNodeValue(item))
+		}
+		return values
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return nil
+		}
+		return node.Value
+	}
+	return nil
+}
+
+// normalizeConfigValue converts the values decoded from JSON or TOML to strings.
+func normalizeConfigValue(value any) any {
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case map[string]any:
+		values := make(map[string]any)
+		for key, item := range value {
+			if item := normalizeConfigValue(item); item != nil {
+				values[key] = item
+			}
+		}
+		return values
+	case []map[string]any:
+		values := make([]any, len(value))
+		for i, item := range value {
+			values[i] = normalizeConfigValue(item)
+		}
+		return values
+	case []any:
+		values := make([]any, len(value))
+		for i, item := range value {
+			values[i] = normalizeConfigValue(item)
+		}
+		return values
+	case time.Time:
+		// TOML has local dates and times, marked by the location.
+		switch value.Location().String() {
+		case "date-local":
+			return value.Format("2006-01-02")
+		case "time-local":
+			return value.Format("15:04:05.999999999")
+		case "datetime-local":
+			return value.Format("2006-01-02T15:04:05.999999999")
+		}
+		return value.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
+
+// table returns the values nested under the keys, or nil if there are none.
+func (cf *ConfigFile) table(keys []string) (map[string]any, error) {
+	table := cf.values
+	for i, key := range keys {
+		value, exists := table[key]
+		if !exists {
+			return nil, nil
+		}
+		var isTable bool
+		table, isTable = value.(map[string]any)
+		if !isTable {
+			return nil, errors.Errorf("%s: %s: expected a table", cf.path, strings.Join(keys[:i+1], "."))
+		}
+	}
+	return table, nil
+}
+
+// Bind returns the flags of a command, followed by a flag applying the values of the config file
+// nested under the keys to them.
+func (cf *ConfigFile) Bind(cliFlags []cli.Flag, keys []string) []cli.Flag {
+	return append(cliFlags[:len(cliFlags):len(cliFlags)], &configFlag{file: cf, keys: keys, flags: cliFlags})
+}
+
+// configFlag applies the values of a config file to the flags of a command.
+//
+// cli checks the required flags before calling any hook, so the values are applied along with the flags.
+// configFlag comes after the flags of the command, and sets the flags that were not set by
+// environment variables. The command line is parsed after all the flags are applied, so it
+// overrides the values.
+//
+// configFlag has no names, and is never shown in the help.
+type configFlag struct {
+	file  *ConfigFile
+	keys  []string
+	flags []cli.Flag
+	// set and applied are the flag set the flag was last applied to, and the flags it set in it.
+	set     *flag.FlagSet
+	applied []cli.Flag
+}
+
+func (f *configFlag) String() string {
+	return ""
+}
+
+func (f *configFlag) Names() []string {
+	return nil
+}
+
+func (f *configFlag) IsSet() bool {
+	return false
+}
+
+// Apply reads the file if needed, and applies its values to the flags.
+//
+// The flags at the top level are applied first, before the path flag is parsed, so they start
+// a new run, reading the default file.
+func (f *configFlag) Apply(set *flag.FlagSet) error {
+	if len(f.keys) == 0 {
+		f.file.passedPath, f.file.isLoaded, f.file.top = "", false, f
+	}
+	if err := f.file.load(); err != nil {
+		return err
+	}
+	f.set, f.applied = set, nil
+	return f.apply(func(*flag.Flag) bool { return false })
+}
+
+// apply applies the values of the file to the flags, skipping the flags that are passed.
+func (f *configFlag) apply(isPassed func(*flag.Flag) bool) error {
+	table, err := f.file.table(f.keys)
+	if err != nil {
+		return err
+	}
+	for _, cliFlag := range f.flags {
+		name := cliFlag.Names()[0]
+		value, exists := table[name]
+		if target := f.set.Lookup(name); !exists || cliFlag.IsSet() || target == nil || isPassed(target) {
+			continue
+		}
+		if err := setConfigValue(f.set, cliFlag, value); err != nil {
+			key := strings.Join(append(f.keys[:len(f.keys):len(f.keys)], name), ".")
+			return errors.Errorf("%s: %s: %s", f.file.path, key, err)
+		}
+		markSet(cliFlag)
+		f.applied = append(f.applied, cliFlag)
+	}
+	return nil
+}
+
+// reapply replaces the values applied from the default file with the values of the passed file,
+// keeping the values of the flags passed before the path flag.
+func (f *configFlag) reapply() error {
+	passed := make(map[*flag.Flag]bool)
+	f.set.Visit(func(target *flag.Flag) {
+		passed[target] = true
+	})
+	for _, cliFlag := range f.applied {
+		if target := f.set.Lookup(cliFlag.Names()[0]); passed[target] {
+			continue
+		}
+		ResetFlags([]cli.Flag{cliFlag})
+		fresh := flag.NewFlagSet("", flag.ContinueOnError)
+		fresh.SetOutput(io.Discard)
+		if err := cliFlag.Apply(fresh); err != nil {
+			return err
+		}
+		for _, name := range cliFlag.Names() {
+			if target := f.set.Lookup(name); target != nil {
+				target.Value = fresh.Lookup(name).Value
+			}
+		}
+	}
+	f.applied = nil
+	return f.apply(func(target *flag.Flag) bool { return passed[target] })
+}
+
+// defaultValue is implemented by values that add to their contents when set more than once.
+type defaultValue interface {
+	// markDefault makes the next Set replace the current contents instead of adding to them.
+	markDefault()
+}
+
+// setConfigValue sets a flag to a value from a config file.
+//
+// Lists set the flag once for every item, and tables set it once for every `KEY=VALUE` entry.
+func setConfigValue(set *flag.FlagSet, cliFlag cli.Flag, value any) error {
+	var texts []string
+	switch value := value.(type) {
+	case string:
+		texts = []string{value}
+	case []any:
+		for _, item := range value {
+			text, isText := item.(string)
+			if !isText {
+				return errors.New("expected a list of values")
+			}
+			texts = append(texts, text)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			text, isText := value[key].(string)
+			if !isText {
+				return errors.Errorf("expected a value for %q", key)
+			}
+			texts = append(texts, key+"="+text)
+		}
+	}
+
+	// Some flags have a separate value for every name, and some share the same value.
+	applied := make(map[flag.Value]bool)
+	for _, name := range cliFlag.Names() {
+		target := set.Lookup(name)
+		if target == nil || applied[target.Value] {
+			continue
+		}
+		applied[target.Value] = true
+		for _, text := range texts {
+			if err := target.Value.Set(text); err != nil {
+				return errors.Errorf("invalid value %q: %s", text, err)
+			}
+		}
+		if value, isDefault := target.Value.(defaultValue); isDefault {
+			value.markDefault()
+		}
+	}
+	return nil
+}
+
+// markSet marks a flag as set, so that it satisfies the required flags check.
+//
+// Like values taken from environment variables, this is undone by ResetFlags.
+func markSet(f cli.Flag) {
+	flagValue := reflect.ValueOf(f)
+	if flagValue.Kind() != reflect.Pointer || flagValue.Elem().Kind() != reflect.Struct {
+		return
+	}
+	hasBeenSet := flagValue.Elem().FieldByName("HasBeenSet")
+	if hasBeenSet.Kind() != reflect.Bool {
+		return
+	}
+	if !lookupInfo(f).initial.IsValid() {
+		saveInitialState(flagValue)
+	}
+	hasBeenSet.SetBool(true)
+}
+
+// ConfigValue converts the value of a flag to a value that can be written to a config file.
+//
+// Numbers, booleans and strings are kept as they are, slices and maps are converted item by item,
+// and other values are written as text.
+func ConfigValue(value flag.Value) any {
+	if value == nil {
+		return nil
+	}
+	getter, isGetter := value.(flag.Getter)
+	if !isGetter {
+		return value.String()
+	}
+	got := reflect.ValueOf(getter.Get())
+	if !got.IsValid() || (got.Kind() == reflect.Pointer && got.IsNil()) {
+		return nil
+	}
+	if isBasic(got.Type()) {
+		return got.Interface()
+	}
+	if got.Type().PkgPath() == "" {
+		switch got.Kind() {
+		case reflect.Slice:
+			items := make([]any, got.Len())
+			for i := range items {
+				items[i] = plainConfigValue(got.Index(i))
+			}
+			return items
+		case reflect.Map:
+			items := make(map[string]any)
+			iter := got.MapRange()
+			for iter.Next() {
+				items[fmt.Sprint(iter.Key().Interface())] = plainConfigValue(iter.Value())
+			}
+			return items
+		}
+	}
+	return value.String()
+}
+
+func plainConfigValue(value reflect.Value) any {
+	if isBasic(value.Type()) {
+		return value.Interface()
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// isBasic is true for the predeclared boolean, numeric and string types.
+func isBasic(t reflect.Type) bool {
+	if t.PkgPath() != "" {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// Encode writes values in the format of the config file, as given by the extension of its path.
+func (cf *ConfigFile) Encode(w io.Writer, values map[string]any) error {
+	switch configFormat(cf.path) {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(values)
+	case "toml":
+		return toml.NewEncoder(w).Encode(values)
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(values); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,45 @@
+package flags
+
+import (
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+	"strings"
+)
+
+// joinFlagNames formats flag names for messages, as in `--a, --b and --c`.
+func joinFlagNames(names []string) string {
+	formatted := make([]string, len(names))
+	for i, name := range names {
+		formatted[i] = "--" + name
+	}
+	if len(formatted) == 1 {
+		return formatted[0]
+	}
+	return strings.Join(formatted[:len(formatted)-1], ", ") + " and " + formatted[len(formatted)-1]
+}
+
+func countSet(c *cli.Context, names []string) int {
+	count := 0
+	for _, name := range names {
+		if c.IsSet(name) {
+			count++
+		}
+	}
+	return count
+}
+
+// CheckExclusive makes sure that at most one of the named flags is set.
+func CheckExclusive(c *cli.Context, names ...string) error {
+	if countSet(c, names) > 1 {
+		return usageError(c, errors.Errorf("%s are mutually exclusive", joinFlagNames(names)))
+	}
+	return nil
+}
+
+// CheckTogether makes sure that either all of the named flags are set, or none of them are.
+func CheckTogether(c *cli.Context, names ...string) error {
+	if count := countSet(c, names); count != 0 && count != len(names) {
+		return usageError(c, errors.Errorf("%s must be used together", joinFlagNames(names)))
+	}
+	return nil
+}
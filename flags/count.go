@@ -0,0 +1,130 @@
+package flags
+
+import (
+	"flag"
+	"github.com/urfave/cli/v2"
+	"strconv"
+)
+
+// Count makes an int flag a counting flag, incremented every time it is passed.
+//
+// Counting flags take no value, so `-v -v -v` (or `-vvv`) counts to 3.
+// goater gives counting flags a single-letter alias, unless they already have one.
+func Count() Option {
+	return func(desc *Description) {
+		desc.Count = true
+	}
+}
+
+// countValue is a flag.Value counting the times it is set.
+//
+// It is a boolean flag as far as the flag package is concerned, so it takes no value.
+// Integer values are still accepted (from environment variables, for example), and set the count.
+type countValue struct {
+	count int
+	// isDefault is true when the count was set by a config file, so that the flags passed
+	// on the command line restart the count, instead of adding to it.
+	isDefault bool
+}
+
+func (v *countValue) Set(s string) error {
+	if s == "true" {
+		if v.isDefault {
+			v.count = 0
+			v.isDefault = false
+		}
+		v.count++
+		return nil
+	}
+	count, err := parseIntValue(s)
+	if err != nil {
+		return err
+	}
+	v.count = count
+	return nil
+}
+
+func (v *countValue) String() string {
+	if v == nil {
+		return "0"
+	}
+	return strconv.Itoa(v.count)
+}
+
+func (v *countValue) Get() any {
+	return v.count
+}
+
+func (v *countValue) IsBoolFlag() bool {
+	return true
+}
+
+func (v *countValue) markDefault() {
+	v.isDefault = true
+}
+
+// countFlag is a cli.GenericFlag holding a countValue.
+//
+// Like parsedFlag, it creates a fresh value every time it is applied.
+// The value is read using cli.Context.Int, like any other int flag.
+type countFlag struct {
+	*cli.GenericFlag
+	start int
+}
+
+func (f *countFlag) Apply(set *flag.FlagSet) error {
+	f.Value = &countValue{count: f.start}
+	f.HasBeenSet = false
+	return f.GenericFlag.Apply(set)
+}
+
+func (f *countFlag) String() string {
+	return cli.FlagStringer(f)
+}
+
+// TakesValue is false, so that the help doesn't show a value placeholder.
+func (f *countFlag) TakesValue() bool {
+	return false
+}
+
+// GetDefaultText shows the starting count, if there is one.
+func (f *countFlag) GetDefaultText() string {
+	if f.DefaultText != "" {
+		return f.DefaultText
+	}
+	if f.start == 0 {
+		return ""
+	}
+	return strconv.Itoa(f.start)
+}
+
+// makeCountFlag creates a counting flag, starting at the default value.
+//
+// Counting flags are never required.
+func makeCountFlag(desc Description) cli.Flag {
+	f := &countFlag{
+		GenericFlag: &cli.GenericFlag{
+			Name:  desc.Name,
+			Usage: desc.Usage,
+		},
+		start: tryCast[int](desc.Default),
+	}
+	f.Value = &countValue{count: f.start}
+	return f
+}
+
+// HasShortFlags returns true if any of the flags counts, or has a single-letter name,
+// so that combining single-letter flags (as in `-vvv`) is needed.
+func HasShortFlags(cliFlags []cli.Flag) bool {
+	for _, f := range cliFlags {
+		if _, isCount := f.(*countFlag); isCount {
+			return true
+		}
+		for _, name := range f.Names() {
+			if len(name) == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package flags
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+	"reflect"
+	"strings"
+)
+
+// choice is a named value an enum flag can take.
+type choice struct {
+	name  string
+	value any
+}
+
+// Choice adds a named value to the values accepted by a flag, making it an enum flag.
+//
+// Enum flags only accept the names of their choices, and are listed with all of them in the help.
+// goater adds the choices for named types with declared constants.
+func Choice(name string, value any) Option {
+	return func(desc *Description) {
+		desc.Choices = append(desc.Choices, choice{name: name, value: value})
+	}
+}
+
+func choiceNames(choices []choice) []string {
+	names := make([]string, len(choices))
+	for i, choice := range choices {
+		names[i] = choice.name
+	}
+	return names
+}
+
+// makeEnumFlag creates a flag accepting only the names of the described choices.
+//
+// Like other flags, it is optional if T is a pointer type, and required if it is not
+// and has no default value.
+func makeEnumFlag[T any](desc Description) cli.Flag {
+	validChoices := strings.Join(choiceNames(desc.Choices), ", ")
+
+	parse := func(s string) (any, error) {
+		for _, choice := range desc.Choices {
+			if choice.name == s {
+				return choice.value, nil
+			}
+		}
+		return nil, errors.Errorf("must be one of: %s", validChoices)
+	}
+	format := func(value any) string {
+		if value == nil {
+			return ""
+		}
+		for _, choice := range desc.Choices {
+			if choice.value == value {
+				return choice.name
+			}
+		}
+		return fmt.Sprint(value)
+	}
+
+	isOptional := reflect.TypeOf(*new(T)).Kind() == reflect.Pointer
+	if isOptional {
+		desc.Default = nil
+	}
+	desc.Usage = strings.TrimSpace(desc.Usage + " (one of: " + validChoices + ")")
+	return newParsedFlag(desc, !isOptional && desc.Default == nil, parse, format)
+}
@@ -1,15 +1,190 @@
 package flags
 
 import (
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
 	"github.com/urfave/cli/v2"
 	"reflect"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func tryCast[T any](from any) T {
 	if from == nil {
 		return *new(T)
 	}
-	return from.(T)
+	if value, isT := from.(T); isT {
+		return value
+	}
+	// Untyped constants passed as defaults (`Default(1)` for a float64 flag, for example)
+	// arrive here with their default type, so we convert them to the flag's type.
+	// goater makes sure the values are representable in the flag's type.
+	return reflect.ValueOf(from).Convert(reflect.TypeOf(*new(T))).Interface().(T)
+}
+
+var errParse = errors.New("parse error")
+
+// numError maps strconv errors to shorter messages, matching those of the standard flag package.
+func numError(err error, typeName string) error {
+	if errors.Is(err, strconv.ErrSyntax) {
+		return errParse
+	}
+	if errors.Is(err, strconv.ErrRange) {
+		return errors.Errorf("value out of range for %s", typeName)
+	}
+	return err
+}
+
+// timeLayout returns functions for parsing and formatting times using the given layout.
+//
+// The layout defaults to time.RFC3339.
+func timeLayout(layout string) (parse func(string) (time.Time, error), format func(time.Time) string) {
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	parse = func(s string) (time.Time, error) {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return time.Time{}, errors.Errorf("expected time in %q layout", layout)
+		}
+		return t, nil
+	}
+	format = func(t time.Time) string {
+		if t.IsZero() {
+			// No point in showing a zero time as a default.
+			return ""
+		}
+		return t.Format(layout)
+	}
+	return parse, format
+}
+
+func parseString(s string) (string, error) {
+	return s, nil
+}
+
+func parseIntValue(s string) (int, error) {
+	i, err := strconv.ParseInt(s, 0, strconv.IntSize)
+	if err != nil {
+		return 0, numError(err, "int")
+	}
+	return int(i), nil
+}
+
+func parseInt[T int8 | int16 | int32 | int64](s string) (T, error) {
+	typ := reflect.TypeOf(*new(T))
+	i, err := strconv.ParseInt(s, 0, typ.Bits())
+	if err != nil {
+		return 0, numError(err, typ.Name())
+	}
+	return T(i), nil
+}
+
+func parseUint[T uint | uint8 | uint16 | uint32 | uint64](s string) (T, error) {
+	typ := reflect.TypeOf(*new(T))
+	u, err := strconv.ParseUint(s, 0, typ.Bits())
+	if err != nil {
+		return 0, numError(err, typ.Name())
+	}
+	return T(u), nil
+}
+
+// Description describes a flag.
+//
+// Name, Usage and Default are common to all flags.
+// The rest are only relevant to specific flag types, and are set using Option values.
+type Description struct {
+	Name    string
+	Usage   string
+	Default any
+	// Layout is the layout used for parsing time.Time flags.
+	Layout string
+	// Separator is used for splitting the values of slice and map flags.
+	Separator string
+	// Choices are the values accepted by enum flags.
+	Choices []choice
+	// Category is the heading the flag is listed under in the help.
+	Category string
+	// Count makes an int flag count the times it is passed.
+	Count bool
+	// Aliases are additional names for the flag.
+	Aliases []string
+	// EnvVars are environment variables the flag takes its value from, if it is not passed.
+	EnvVars []string
+	// Hidden flags are left out of the help.
+	Hidden bool
+	// Deprecated is a message shown when a deprecated flag is used, and empty for other flags.
+	Deprecated string
+	// Validators are the constraints on the values of the flag, checked using ValidateFlag.
+	Validators []validator
+	// File flags take file paths, and are completed with file paths by shells.
+	File bool
+	// Complete provides the candidates for completing the flag's value, if it is set.
+	Complete CompleteFunc
+}
+
+// Option sets an optional part of a flag's Description.
+type Option func(desc *Description)
+
+// Layout sets the layout used for parsing time.Time flags.
+func Layout(layout string) Option {
+	return func(desc *Description) {
+		desc.Layout = layout
+	}
+}
+
+// Separator sets the separator used to split a single value of a slice or map flag into multiple values.
+func Separator(separator string) Option {
+	return func(desc *Description) {
+		desc.Separator = separator
+	}
+}
+
+// Category sets the heading a flag is listed under in the help.
+func Category(category string) Option {
+	return func(desc *Description) {
+		desc.Category = category
+	}
+}
+
+// Alias adds alternative names for a flag, like a single-letter short name.
+func Alias(aliases ...string) Option {
+	return func(desc *Description) {
+		desc.Aliases = append(desc.Aliases, aliases...)
+	}
+}
+
+// EnvVar binds a flag to environment variables, used when the flag is not passed.
+//
+// The first variable that is set is used, and takes precedence over the default value.
+func EnvVar(envVars ...string) Option {
+	return func(desc *Description) {
+		desc.EnvVars = append(desc.EnvVars, envVars...)
+	}
+}
+
+// Hidden leaves a flag out of the help.
+func Hidden() Option {
+	return func(desc *Description) {
+		desc.Hidden = true
+	}
+}
+
+// File marks a flag as taking file paths, so that shells complete its values with file paths.
+func File() Option {
+	return func(desc *Description) {
+		desc.File = true
+	}
+}
+
+// Deprecated marks a flag as deprecated. The flag keeps working, but using it prints
+// a warning with the given message.
+func Deprecated(message string) Option {
+	return func(desc *Description) {
+		desc.Deprecated = message
+	}
 }
 
 // TypeHandler defines the handling of a specific cli.Flag type.
@@ -21,6 +196,16 @@ type TypeHandler interface {
 	GetFlag(c *cli.Context, name string) any
 }
 
+// DescribedTypeHandler is a TypeHandler that creates flags from their full Description,
+// for types with options of their own, like the Layout of time.Time flags.
+//
+// The parts of the description that are common to all flags, like aliases and environment variables,
+// are set on the flags of every handler, so handlers of other types only need to implement TypeHandler.
+type DescribedTypeHandler interface {
+	TypeHandler
+	MakeDescribedFlag(desc Description) cli.Flag
+}
+
 // flagHandlers is the registry of type handlers for flags.
 var flagHandlers map[reflect.Type]TypeHandler
 
@@ -43,12 +228,16 @@ func RegisterTypeHandler[T any](handler TypeHandler) {
 }
 
 type typeHandlerImpl struct {
-	makeFlag func(name, usage string, defaultValue any) cli.Flag
+	makeFlag func(desc Description) cli.Flag
 	getFlag  func(c *cli.Context, name string) any
 }
 
 func (impl *typeHandlerImpl) MakeFlag(name, usage string, defaultValue any) cli.Flag {
-	return impl.makeFlag(name, usage, defaultValue)
+	return impl.makeFlag(Description{Name: name, Usage: usage, Default: defaultValue})
+}
+
+func (impl *typeHandlerImpl) MakeDescribedFlag(desc Description) cli.Flag {
+	return impl.makeFlag(desc)
 }
 
 func (impl *typeHandlerImpl) GetFlag(c *cli.Context, name string) any {
@@ -58,18 +247,18 @@ func (impl *typeHandlerImpl) GetFlag(c *cli.Context, name string) any {
 func init() {
 	// Register all the default types.
 	RegisterTypeHandler[int](&typeHandlerImpl{
-		makeFlag: func(name, usage string, defaultValue any) cli.Flag {
-			if defaultValue == nil {
+		makeFlag: func(desc Description) cli.Flag {
+			if desc.Default == nil {
 				return &cli.IntFlag{
-					Name:     name,
-					Usage:    usage,
+					Name:     desc.Name,
+					Usage:    desc.Usage,
 					Required: true,
 				}
 			}
 			return &cli.IntFlag{
-				Name:  name,
-				Usage: usage,
-				Value: tryCast[int](defaultValue),
+				Name:  desc.Name,
+				Usage: desc.Usage,
+				Value: tryCast[int](desc.Default),
 			}
 		},
 		getFlag: func(c *cli.Context, name string) any {
@@ -78,10 +267,10 @@ func init() {
 	})
 
 	RegisterTypeHandler[*int](&typeHandlerImpl{
-		makeFlag: func(name, usage string, defaultValue any) cli.Flag {
+		makeFlag: func(desc Description) cli.Flag {
 			return &cli.IntFlag{
-				Name:     name,
-				Usage:    usage,
+				Name:     desc.Name,
+				Usage:    desc.Usage,
 				Required: false,
 			}
 		},
@@ -94,18 +283,18 @@ func init() {
 		},
 	})
 	RegisterTypeHandler[string](&typeHandlerImpl{
-		makeFlag: func(name, usage string, defaultValue any) cli.Flag {
-			if defaultValue == nil {
+		makeFlag: func(desc Description) cli.Flag {
+			if desc.Default == nil {
 				return &cli.StringFlag{
-					Name:     name,
-					Usage:    usage,
+					Name:     desc.Name,
+					Usage:    desc.Usage,
 					Required: true,
 				}
 			}
 			return &cli.StringFlag{
-				Name:  name,
-				Usage: usage,
-				Value: tryCast[string](defaultValue),
+				Name:  desc.Name,
+				Usage: desc.Usage,
+				Value: tryCast[string](desc.Default),
 			}
 		},
 		getFlag: func(c *cli.Context, name string) any {
@@ -113,10 +302,10 @@ func init() {
 		},
 	})
 	RegisterTypeHandler[*string](&typeHandlerImpl{
-		makeFlag: func(name, usage string, defaultValue any) cli.Flag {
+		makeFlag: func(desc Description) cli.Flag {
 			return &cli.StringFlag{
-				Name:     name,
-				Usage:    usage,
+				Name:     desc.Name,
+				Usage:    desc.Usage,
 				Required: false,
 			}
 		},
@@ -128,12 +317,138 @@ func init() {
 			return nil
 		},
 	})
+	RegisterTypeHandler[float64](&typeHandlerImpl{
+		makeFlag: func(desc Description) cli.Flag {
+			if desc.Default == nil {
+				return &cli.Float64Flag{
+					Name:     desc.Name,
+					Usage:    desc.Usage,
+					Required: true,
+				}
+			}
+			return &cli.Float64Flag{
+				Name:  desc.Name,
+				Usage: desc.Usage,
+				Value: tryCast[float64](desc.Default),
+			}
+		},
+		getFlag: func(c *cli.Context, name string) any {
+			return c.Float64(name)
+		},
+	})
+	RegisterTypeHandler[*float64](&typeHandlerImpl{
+		makeFlag: func(desc Description) cli.Flag {
+			return &cli.Float64Flag{
+				Name:     desc.Name,
+				Usage:    desc.Usage,
+				Required: false,
+			}
+		},
+		getFlag: func(c *cli.Context, name string) any {
+			if c.IsSet(name) {
+				f := c.Float64(name)
+				return &f
+			}
+			return nil
+		},
+	})
+	// The cli package has no float32 flags, so we parse them ourselves.
+	registerParsedType(func(s string) (float32, error) {
+		f, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			return 0, numError(err, "float32")
+		}
+		return float32(f), nil
+	})
+	// The sized integer types are parsed with their exact size, so that out-of-range
+	// values are rejected instead of being truncated.
+	registerParsedType(parseInt[int8])
+	registerParsedType(parseInt[int16])
+	registerParsedType(parseInt[int32])
+	registerParsedType(parseInt[int64])
+	registerParsedType(parseUint[uint])
+	registerParsedType(parseUint[uint8])
+	registerParsedType(parseUint[uint16])
+	registerParsedType(parseUint[uint32])
+	registerParsedType(parseUint[uint64])
+	RegisterTypeHandler[time.Duration](&typeHandlerImpl{
+		makeFlag: func(desc Description) cli.Flag {
+			if desc.Default == nil {
+				return &cli.DurationFlag{
+					Name:     desc.Name,
+					Usage:    desc.Usage,
+					Required: true,
+				}
+			}
+			return &cli.DurationFlag{
+				Name:  desc.Name,
+				Usage: desc.Usage,
+				Value: tryCast[time.Duration](desc.Default),
+			}
+		},
+		getFlag: func(c *cli.Context, name string) any {
+			return c.Duration(name)
+		},
+	})
+	RegisterTypeHandler[*time.Duration](&typeHandlerImpl{
+		makeFlag: func(desc Description) cli.Flag {
+			return &cli.DurationFlag{
+				Name:     desc.Name,
+				Usage:    desc.Usage,
+				Required: false,
+			}
+		},
+		getFlag: func(c *cli.Context, name string) any {
+			if c.IsSet(name) {
+				d := c.Duration(name)
+				return &d
+			}
+			return nil
+		},
+	})
+	// We don't use cli.TimestampFlag as it keeps its value between runs.
+	RegisterTypeHandler[time.Time](&typeHandlerImpl{
+		makeFlag: func(desc Description) cli.Flag {
+			parse, format := timeLayout(desc.Layout)
+			return newParsedFlag(desc, desc.Default == nil, parse, format)
+		},
+		getFlag: func(c *cli.Context, name string) any {
+			return getParsedValue[time.Time](c, name)
+		},
+	})
+	RegisterTypeHandler[*time.Time](&typeHandlerImpl{
+		makeFlag: func(desc Description) cli.Flag {
+			parse, format := timeLayout(desc.Layout)
+			desc.Default = nil
+			return newParsedFlag(desc, false, parse, format)
+		},
+		getFlag: func(c *cli.Context, name string) any {
+			if c.IsSet(name) {
+				t := getParsedValue[time.Time](c, name)
+				return &t
+			}
+			return nil
+		},
+	})
+	// Slices are repeated flags, collecting all the values.
+	registerSliceType(parseString)
+	registerSliceType(parseIntValue)
+	registerSliceType(func(s string) (float64, error) {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, numError(err, "float64")
+		}
+		return f, nil
+	})
+	// Maps are repeated flags, collecting KEY=VALUE pairs.
+	registerMapType(parseString)
+	registerMapType(parseIntValue)
 	RegisterTypeHandler[bool](&typeHandlerImpl{
-		makeFlag: func(name, usage string, defaultValue any) cli.Flag {
+		makeFlag: func(desc Description) cli.Flag {
 			return &cli.BoolFlag{
-				Name:  name,
-				Usage: usage,
-				Value: tryCast[bool](defaultValue),
+				Name:  desc.Name,
+				Usage: desc.Usage,
+				Value: tryCast[bool](desc.Default),
 			}
 		},
 		getFlag: func(c *cli.Context, name string) any {
@@ -142,20 +457,325 @@ func init() {
 	})
 }
 
+// IsRegistered reports whether there is a type handler for a type, given its name
+// as formatted by reflect.Type.String (`*time.Duration`, for example).
+//
+// goater uses this to know which types are handled, as it only knows types by their names.
+func IsRegistered(typeName string) bool {
+	for handledType := range flagHandlers {
+		if handledType.String() == typeName {
+			return true
+		}
+	}
+	return false
+}
+
+// setCommonFields sets the parts of the description that are common to all the cli flags,
+// so that type handlers don't have to handle them.
+//
+// All the cli flag types share the same field names, so we set them by name.
+func setCommonFields(f cli.Flag, desc Description) cli.Flag {
+	flagValue := reflect.ValueOf(f)
+	if flagValue.Kind() != reflect.Pointer || flagValue.Elem().Kind() != reflect.Struct {
+		return f
+	}
+	if category := flagValue.Elem().FieldByName("Category"); desc.Category != "" && category.Kind() == reflect.String {
+		category.SetString(desc.Category)
+	}
+	if hidden := flagValue.Elem().FieldByName("Hidden"); desc.Hidden && hidden.Kind() == reflect.Bool {
+		hidden.SetBool(true)
+	}
+	if usage := flagValue.Elem().FieldByName("Usage"); desc.Deprecated != "" && usage.Kind() == reflect.String {
+		usage.SetString(strings.TrimSpace(usage.String() + " (deprecated: " + desc.Deprecated + ")"))
+		infoOf(f).deprecation = desc.Deprecated
+	}
+	if takesFile := flagValue.Elem().FieldByName("TakesFile"); desc.File && takesFile.Kind() == reflect.Bool {
+		takesFile.SetBool(true)
+	}
+	if len(desc.Choices) != 0 {
+		infoOf(f).choices = choiceNames(desc.Choices)
+	}
+	if desc.Complete != nil {
+		infoOf(f).complete = desc.Complete
+	}
+	appendStrings(flagValue, "Aliases", desc.Aliases)
+	appendStrings(flagValue, "EnvVars", desc.EnvVars)
+	if len(desc.EnvVars) != 0 {
+		saveInitialState(flagValue)
+	}
+	return f
+}
+
+// flagInfo holds what we know about a flag beyond its cli fields.
+//
+// The flags created by the type handlers are often cli's own flag types, which have nowhere
+// to keep it, so it is kept in a single table, and copied as a whole when a flag is cloned.
+type flagInfo struct {
+	// typeName is the Go type of a flag created by MakeFlag, for documentation.
+	typeName string
+	// choices are the names of the choices of an enum flag, for shell completion.
+	choices []string
+	// complete completes the values of the flag, when asked by the shell.
+	complete CompleteFunc
+	// deprecation is the message of a deprecated flag.
+	deprecation string
+	// initial is a copy of a flag bound to environment variables or set by config files,
+	// taken before it is used.
+	//
+	// The cli flags store values taken from environment variables in their Value and HasBeenSet fields,
+	// which are otherwise left untouched by runs of the app. To keep the values from leaking into
+	// the next run, we restore the flags to their initial states before every run.
+	initial reflect.Value
+}
+
+var flagInfos = make(map[cli.Flag]*flagInfo)
+
+// infoOf returns the info of a flag, to be filled in.
+func infoOf(f cli.Flag) *flagInfo {
+	info, exists := flagInfos[f]
+	if !exists {
+		info = &flagInfo{}
+		flagInfos[f] = info
+	}
+	return info
+}
+
+// lookupInfo returns the info of a flag, which is empty for flags we know nothing about.
+func lookupInfo(f cli.Flag) flagInfo {
+	if info, exists := flagInfos[f]; exists {
+		return *info
+	}
+	return flagInfo{}
+}
+
+// DeprecationMessage returns the message of a deprecated flag, or an empty string for other flags.
+func DeprecationMessage(f cli.Flag) string {
+	return lookupInfo(f).deprecation
+}
+
+// WarnDeprecated prints a warning to the app's ErrWriter for every deprecated flag that is set.
+func WarnDeprecated(c *cli.Context, cliFlags []cli.Flag) {
+	for _, f := range cliFlags {
+		message := DeprecationMessage(f)
+		if message != "" && c.IsSet(f.Names()[0]) {
+			fmt.Fprintf(c.App.ErrWriter, "Warning: flag --%s is deprecated: %s\n", f.Names()[0], message)
+		}
+	}
+}
+
+func saveInitialState(flagValue reflect.Value) {
+	initial := reflect.New(flagValue.Elem().Type())
+	initial.Elem().Set(flagValue.Elem())
+	infoOf(flagValue.Interface().(cli.Flag)).initial = initial
+}
+
+// ResetFlags restores flags bound to environment variables or set by config files to their initial states.
+func ResetFlags(cliFlags []cli.Flag) {
+	for _, f := range cliFlags {
+		if initial := lookupInfo(f).initial; initial.IsValid() {
+			reflect.ValueOf(f).Elem().Set(initial.Elem())
+		}
+	}
+}
+
+// appendStrings appends values to a []string field of a flag, given a pointer to the flag.
+func appendStrings(flagValue reflect.Value, fieldName string, values []string) {
+	field := flagValue.Elem().FieldByName(fieldName)
+	if len(values) == 0 || !field.IsValid() || field.Type() != reflect.TypeOf(values) {
+		return
+	}
+	field.Set(reflect.AppendSlice(field, reflect.ValueOf(values)))
+}
+
+// EnvVarName derives the name of an environment variable from a prefix and a flag name,
+// as in `APP_DB_HOST` for the `db-host` flag with the `APP` prefix.
+func EnvVarName(prefix string, name string) string {
+	name = strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+	if prefix == "" {
+		return name
+	}
+	return strings.TrimSuffix(prefix, "_") + "_" + name
+}
+
+// CloneFlags copies flags, so that they can be changed without changing the originals.
+//
+// The flags of a function are shared by every app and command created from it, so they are
+// cloned before being bound to anything specific to an app, like environment variables.
+// The clones keep everything we know about the originals, like their types and choices.
+// Flags that are not pointers to structs are kept as they are.
+func CloneFlags(cliFlags []cli.Flag) []cli.Flag {
+	clones := make([]cli.Flag, len(cliFlags))
+	cloneByFlag := make(map[cli.Flag]cli.Flag)
+	for i, f := range cliFlags {
+		clones[i] = cloneFlag(f)
+		cloneByFlag[f] = clones[i]
+	}
+	// The config file flag applies values to the flags before it, so it must apply them to the clones.
+	for i, f := range clones {
+		if config, isConfig := f.(*configFlag); isConfig {
+			clone := *config
+			clone.flags = make([]cli.Flag, len(config.flags))
+			for j, configured := range config.flags {
+				clone.flags[j] = cloneByFlag[configured]
+				if clone.flags[j] == nil {
+					clone.flags[j] = configured
+				}
+			}
+			clones[i] = &clone
+		}
+	}
+	return clones
+}
+
+func cloneFlag(f cli.Flag) cli.Flag {
+	flagValue := reflect.ValueOf(f)
+	if flagValue.Kind() != reflect.Pointer || flagValue.Elem().Kind() != reflect.Struct {
+		return f
+	}
+	// A flag bound to environment variables may hold values taken from them, so we clone its initial state.
+	info, hasInfo := flagInfos[f]
+	if !hasInfo {
+		return cloneStruct(flagValue).Interface().(cli.Flag)
+	}
+	source := flagValue
+	if info.initial.IsValid() {
+		source = info.initial
+	}
+	cloneValue := cloneStruct(source)
+	clone := cloneValue.Interface().(cli.Flag)
+	cloneInfo := *info
+	flagInfos[clone] = &cloneInfo
+	if info.initial.IsValid() {
+		saveInitialState(cloneValue)
+	}
+	return clone
+}
+
+// cloneStruct copies a struct, given a pointer to it.
+//
+// Embedded pointers to structs, like the *cli.GenericFlag of the flags defined here, are copied as well,
+// and so are string slices, like Aliases and EnvVars, so that appending to them doesn't change the original.
+func cloneStruct(structPointer reflect.Value) reflect.Value {
+	clone := reflect.New(structPointer.Elem().Type())
+	clone.Elem().Set(structPointer.Elem())
+	for i := 0; i < clone.Elem().NumField(); i++ {
+		field := clone.Elem().Field(i)
+		if !field.CanSet() || (field.Kind() == reflect.Pointer || field.Kind() == reflect.Slice) && field.IsNil() {
+			continue
+		}
+		structField := clone.Elem().Type().Field(i)
+		switch {
+		case structField.Anonymous && field.Kind() == reflect.Pointer && field.Elem().Kind() == reflect.Struct:
+			field.Set(cloneStruct(field))
+		case field.Type() == reflect.TypeOf([]string(nil)):
+			field.Set(reflect.AppendSlice(reflect.MakeSlice(field.Type(), 0, field.Len()), field))
+		}
+	}
+	return clone
+}
+
+// BindEnvPrefix binds flags that have no environment variables to variables derived from
+// their names, using EnvVarName.
+//
+// The flags are changed in place, so flags shared with other apps must be cloned first, using CloneFlags.
+func BindEnvPrefix(cliFlags []cli.Flag, prefix string) {
+	for _, f := range cliFlags {
+		flagValue := reflect.ValueOf(f)
+		if flagValue.Kind() != reflect.Pointer || flagValue.Elem().Kind() != reflect.Struct {
+			continue
+		}
+		envVars := flagValue.Elem().FieldByName("EnvVars")
+		if !envVars.IsValid() || envVars.Len() != 0 {
+			continue
+		}
+		appendStrings(flagValue, "EnvVars", []string{EnvVarName(prefix, f.Names()[0])})
+		saveInitialState(flagValue)
+	}
+}
+
 // MakeFlag creates a flag from a type and description values.
-func MakeFlag[T any](name string, usage string, defaultValue any) cli.Flag {
+func MakeFlag[T any](name string, usage string, defaultValue any, options ...Option) cli.Flag {
+	desc := Description{Name: name, Usage: usage, Default: defaultValue}
+	for _, option := range options {
+		option(&desc)
+	}
+	desc.Usage = describeValidators(desc)
+	f := setCommonFields(makeFlag[T](desc), desc)
+	infoOf(f).typeName = reflect.TypeOf(*new(T)).String()
+	return f
+}
+
+func makeFlag[T any](desc Description) cli.Flag {
+	if desc.Count {
+		return makeCountFlag(desc)
+	}
+	if len(desc.Choices) != 0 {
+		return makeEnumFlag[T](desc)
+	}
 	handler, exists := flagHandlers[reflect.TypeOf(*new(T))]
-	if !exists {
-		panic("Missing handler for type")
+	if described, isDescribed := handler.(DescribedTypeHandler); isDescribed {
+		return described.MakeDescribedFlag(desc)
 	}
-	return handler.MakeFlag(name, usage, defaultValue)
+	if exists {
+		return handler.MakeFlag(desc.Name, desc.Usage, desc.Default)
+	}
+	if valueType, isOptional, isText := textValueType[T](); isText {
+		return makeTextFlag(desc, valueType, isOptional)
+	}
+	panic("Missing handler for type " + reflect.TypeOf(*new(T)).String())
+}
+
+// TypeName returns the Go type of a flag, as in `int` or `*time.Duration`.
+//
+// For flags not created by MakeFlag, it is the type of the flag's Value field, if it has one.
+func TypeName(f cli.Flag) string {
+	if typeName := lookupInfo(f).typeName; typeName != "" {
+		return typeName
+	}
+	flagValue := reflect.ValueOf(f)
+	if flagValue.Kind() != reflect.Pointer || flagValue.Elem().Kind() != reflect.Struct {
+		return ""
+	}
+	if value := flagValue.Elem().FieldByName("Value"); value.IsValid() {
+		return value.Type().String()
+	}
+	return ""
+}
+
+// getValue gets the value of a flag that has no type handler, using the flag.Getter interface.
+//
+// The flag may hold either a T, or a pointer to T.
+// If T is a pointer type, the flag is optional and may hold the value T points to.
+func getValue[T any](c *cli.Context, name string) T {
+	getter, isGetter := c.Generic(name).(flag.Getter)
+	if !isGetter {
+		return *new(T)
+	}
+	value := getter.Get()
+	if typedValue, isT := value.(T); isT {
+		return typedValue
+	}
+	if value == nil {
+		return *new(T)
+	}
+	typ := reflect.TypeOf(*new(T))
+	if reflect.TypeOf(value) == reflect.PointerTo(typ) {
+		return reflect.ValueOf(value).Elem().Interface().(T)
+	}
+	if typ.Kind() != reflect.Pointer || !c.IsSet(name) {
+		return *new(T)
+	}
+	pointer := reflect.New(typ.Elem())
+	pointer.Elem().Set(reflect.ValueOf(value))
+	return pointer.Interface().(T)
 }
 
 // GetFlag gets the value of a flag by its name.
 func GetFlag[T any](c *cli.Context, name string) T {
 	handler, exists := flagHandlers[reflect.TypeOf(*new(T))]
 	if !exists {
-		panic("oh no!")
+		// Flags without type handlers, like enum flags and text flags, hold their values directly.
+		return getValue[T](c, name)
 	}
 	flag := handler.GetFlag(c, name)
 	if flag == nil {
@@ -165,6 +785,17 @@ func GetFlag[T any](c *cli.Context, name string) T {
 	return flag.(T)
 }
 
+// MustParse parses a value the same way as a flag of type T, and panics if it is invalid.
+//
+// goater uses it for default values written as text, like the ones in struct tags.
+func MustParse[T any](s string, options ...Option) T {
+	value, err := parseArg[T]("value", &s, nil, options...)
+	if err != nil {
+		panic(fmt.Sprintf("invalid value %q for %s: %s", s, reflect.TypeOf(*new(T)), err))
+	}
+	return value
+}
+
 /*
 The codegen part will create the calls to `MakeXXXFlag` with the correct `FlagDescription` struct to pass in.
 This is done to allow separating the descriptor fields from the actual struct fields.
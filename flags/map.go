@@ -0,0 +1,152 @@
+package flags
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+	"sort"
+	"strings"
+)
+
+// mapValue is a cli.Generic that collects the KEY=VALUE pairs of a repeated flag.
+//
+// When the same key is provided more than once, the last value wins.
+type mapValue[V any] struct {
+	values map[string]V
+	// isDefault is true until the flag is first set, so that the default values are
+	// replaced by the provided ones, instead of being added to.
+	isDefault bool
+	parse     func(string) (V, error)
+	// separator is used to split a single flag value into multiple pairs.
+	// If empty, values are not split.
+	separator string
+}
+
+func (v *mapValue[V]) Set(s string) error {
+	entries := []string{s}
+	if v.separator != "" {
+		entries = strings.Split(s, v.separator)
+	}
+	values := make(map[string]V)
+	for _, entry := range entries {
+		key, rawValue, found := strings.Cut(entry, "=")
+		if !found || key == "" {
+			return errors.Errorf("expected KEY=VALUE, got %q", entry)
+		}
+		value, err := v.parse(rawValue)
+		if err != nil {
+			return errors.Wrapf(err, "key %q", key)
+		}
+		values[key] = value
+	}
+	if v.isDefault {
+		v.values = make(map[string]V)
+		v.isDefault = false
+	}
+	for key, value := range values {
+		v.values[key] = value
+	}
+	return nil
+}
+
+func (v *mapValue[V]) String() string {
+	if v == nil {
+		return ""
+	}
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = fmt.Sprintf("%s=%v", key, v.values[key])
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func (v *mapValue[V]) Get() any {
+	return v.values
+}
+
+func (v *mapValue[V]) markDefault() {
+	v.isDefault = true
+}
+
+// mapFlag is a cli.GenericFlag holding a mapValue.
+//
+// Like parsedFlag, it creates a fresh value every time it is applied.
+type mapFlag[V any] struct {
+	*cli.GenericFlag
+	defaultValue map[string]V
+	parse        func(string) (V, error)
+	separator    string
+}
+
+func (f *mapFlag[V]) newValue() *mapValue[V] {
+	values := make(map[string]V, len(f.defaultValue))
+	for key, value := range f.defaultValue {
+		values[key] = value
+	}
+	return &mapValue[V]{
+		values:    values,
+		isDefault: true,
+		parse:     f.parse,
+		separator: f.separator,
+	}
+}
+
+func (f *mapFlag[V]) Apply(set *flag.FlagSet) error {
+	f.Value = f.newValue()
+	f.HasBeenSet = false
+	return f.GenericFlag.Apply(set)
+}
+
+// String shows the flag as a repeated flag, taking KEY=VALUE pairs.
+func (f *mapFlag[V]) String() string {
+	names, usage, _ := strings.Cut(cli.FlagStringer(f), "\t")
+	return strings.ReplaceAll(names, " value", " KEY=VALUE") + "\t" + usage
+}
+
+// GetDefaultText shows the default value of the flag, instead of its current value.
+func (f *mapFlag[V]) GetDefaultText() string {
+	if f.DefaultText != "" {
+		return f.DefaultText
+	}
+	return f.newValue().String()
+}
+
+// IsSliceFlag implements cli.DocGenerationSliceFlag.
+func (f *mapFlag[V]) IsSliceFlag() bool {
+	return true
+}
+
+// registerMapType registers a handler for map[string]V, using parse to parse the individual values.
+//
+// Map flags are never required. When not provided, they hold their default value
+// or an empty map.
+func registerMapType[V any](parse func(string) (V, error)) {
+	RegisterTypeHandler[map[string]V](&typeHandlerImpl{
+		makeFlag: func(desc Description) cli.Flag {
+			f := &mapFlag[V]{
+				GenericFlag: &cli.GenericFlag{
+					Name:  desc.Name,
+					Usage: desc.Usage,
+				},
+				defaultValue: tryCast[map[string]V](desc.Default),
+				parse:        parse,
+				separator:    desc.Separator,
+			}
+			f.Value = f.newValue()
+			return f
+		},
+		getFlag: func(c *cli.Context, name string) any {
+			value, isMap := c.Generic(name).(*mapValue[V])
+			if !isMap {
+				return map[string]V{}
+			}
+			return value.values
+		},
+	})
+}
@@ -0,0 +1,143 @@
+package flags
+
+import (
+	"flag"
+	"fmt"
+	"github.com/urfave/cli/v2"
+)
+
+// parsedValue is a cli.Generic that holds a value of type T, parsed from a string.
+type parsedValue[T any] struct {
+	value  T
+	parse  func(string) (T, error)
+	format func(T) string
+}
+
+func (v *parsedValue[T]) Set(s string) error {
+	value, err := v.parse(s)
+	if err != nil {
+		return err
+	}
+	v.value = value
+	return nil
+}
+
+func (v *parsedValue[T]) String() string {
+	if v == nil {
+		return ""
+	}
+	if v.format != nil {
+		return v.format(v.value)
+	}
+	return fmt.Sprint(v.value)
+}
+
+func (v *parsedValue[T]) Get() any {
+	return v.value
+}
+
+// parsedFlag is a cli.GenericFlag holding a parsedValue.
+//
+// Flags are created once, when the action is registered, and are reused for every
+// run of the app. To avoid leaking values between runs, a fresh value is created
+// every time the flag is applied.
+type parsedFlag[T any] struct {
+	*cli.GenericFlag
+	defaultValue T
+	parse        func(string) (T, error)
+	// format formats values for display, and defaults to fmt.Sprint when nil.
+	format func(T) string
+}
+
+func (f *parsedFlag[T]) newValue() *parsedValue[T] {
+	return &parsedValue[T]{value: f.defaultValue, parse: f.parse, format: f.format}
+}
+
+func (f *parsedFlag[T]) Apply(set *flag.FlagSet) error {
+	f.Value = f.newValue()
+	// Values taken from environment variables mark the flag as set, so we start over.
+	f.HasBeenSet = false
+	return f.GenericFlag.Apply(set)
+}
+
+// String is defined here, and not on the embedded cli.GenericFlag, so that the
+// help text uses the methods defined here.
+func (f *parsedFlag[T]) String() string {
+	return cli.FlagStringer(f)
+}
+
+// GetDefaultText shows the default value of the flag, instead of its current value.
+func (f *parsedFlag[T]) GetDefaultText() string {
+	if f.DefaultText != "" {
+		return f.DefaultText
+	}
+	return f.newValue().String()
+}
+
+func newParsedFlag[T any](desc Description, required bool, parse func(string) (T, error), format func(T) string) *parsedFlag[T] {
+	f := &parsedFlag[T]{
+		GenericFlag: &cli.GenericFlag{
+			Name:     desc.Name,
+			Usage:    desc.Usage,
+			Required: required,
+		},
+		defaultValue: parseDefault(desc, parse),
+		parse:        parse,
+		format:       format,
+	}
+	f.Value = f.newValue()
+	return f
+}
+
+// parseDefault converts the default value of a flag to T.
+//
+// Default values written as strings, like `Default("2024-01-01")` for a time.Time flag,
+// are parsed the same way as the values passed on the command line.
+func parseDefault[T any](desc Description, parse func(string) (T, error)) T {
+	text, isString := desc.Default.(string)
+	if _, isT := desc.Default.(T); !isString || isT {
+		return tryCast[T](desc.Default)
+	}
+	value, err := parse(text)
+	if err != nil {
+		panic(fmt.Sprintf("invalid default value %q for flag %s: %s", text, desc.Name, err))
+	}
+	return value
+}
+
+// getParsedValue gets the value of a parsedFlag by its name.
+func getParsedValue[T any](c *cli.Context, name string) T {
+	value, isParsed := c.Generic(name).(*parsedValue[T])
+	if !isParsed {
+		return *new(T)
+	}
+	return value.value
+}
+
+// registerParsedType registers handlers for both T and *T, using parse to parse flag values.
+//
+// The handler for T creates a required flag unless a default value is provided.
+// The handler for *T creates an optional flag, and returns nil when the flag is not set.
+func registerParsedType[T any](parse func(string) (T, error)) {
+	RegisterTypeHandler[T](&typeHandlerImpl{
+		makeFlag: func(desc Description) cli.Flag {
+			return newParsedFlag(desc, desc.Default == nil, parse, nil)
+		},
+		getFlag: func(c *cli.Context, name string) any {
+			return getParsedValue[T](c, name)
+		},
+	})
+	RegisterTypeHandler[*T](&typeHandlerImpl{
+		makeFlag: func(desc Description) cli.Flag {
+			desc.Default = nil
+			return newParsedFlag(desc, false, parse, nil)
+		},
+		getFlag: func(c *cli.Context, name string) any {
+			if c.IsSet(name) {
+				value := getParsedValue[T](c, name)
+				return &value
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,137 @@
+package flags
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+	"strings"
+)
+
+// sliceValue is a cli.Generic that collects the values of a repeated flag.
+type sliceValue[E any] struct {
+	values []E
+	// isDefault is true until the flag is first set, so that the default values are
+	// replaced by the provided ones, instead of being added to.
+	isDefault bool
+	parse     func(string) (E, error)
+	// separator is used to split a single flag value into multiple values.
+	// If empty, values are not split.
+	separator string
+}
+
+func (v *sliceValue[E]) Set(s string) error {
+	parts := []string{s}
+	if v.separator != "" {
+		parts = strings.Split(s, v.separator)
+	}
+	var values []E
+	for _, part := range parts {
+		value, err := v.parse(part)
+		if err != nil {
+			if len(parts) > 1 {
+				return errors.Wrapf(err, "item %q", part)
+			}
+			return err
+		}
+		values = append(values, value)
+	}
+	if v.isDefault {
+		v.values = nil
+		v.isDefault = false
+	}
+	v.values = append(v.values, values...)
+	return nil
+}
+
+func (v *sliceValue[E]) String() string {
+	if v == nil {
+		return ""
+	}
+	formatted := make([]string, len(v.values))
+	for i, value := range v.values {
+		formatted[i] = fmt.Sprint(value)
+	}
+	return strings.Join(formatted, ", ")
+}
+
+func (v *sliceValue[E]) Get() any {
+	return v.values
+}
+
+func (v *sliceValue[E]) markDefault() {
+	v.isDefault = true
+}
+
+// sliceFlag is a cli.GenericFlag holding a sliceValue.
+//
+// Like parsedFlag, it creates a fresh value every time it is applied.
+type sliceFlag[E any] struct {
+	*cli.GenericFlag
+	defaultValue []E
+	parse        func(string) (E, error)
+	separator    string
+}
+
+func (f *sliceFlag[E]) newValue() *sliceValue[E] {
+	return &sliceValue[E]{
+		values:    append([]E(nil), f.defaultValue...),
+		isDefault: true,
+		parse:     f.parse,
+		separator: f.separator,
+	}
+}
+
+func (f *sliceFlag[E]) Apply(set *flag.FlagSet) error {
+	f.Value = f.newValue()
+	f.HasBeenSet = false
+	return f.GenericFlag.Apply(set)
+}
+
+// String is defined here, and not on the embedded cli.GenericFlag, so that the
+// help text uses the methods defined here.
+func (f *sliceFlag[E]) String() string {
+	return cli.FlagStringer(f)
+}
+
+// GetDefaultText shows the default value of the flag, instead of its current value.
+func (f *sliceFlag[E]) GetDefaultText() string {
+	if f.DefaultText != "" {
+		return f.DefaultText
+	}
+	return f.newValue().String()
+}
+
+// IsSliceFlag implements cli.DocGenerationSliceFlag.
+func (f *sliceFlag[E]) IsSliceFlag() bool {
+	return true
+}
+
+// registerSliceType registers a handler for []E, using parse to parse the individual values.
+//
+// Slice flags are never required. When not provided, they hold their default value
+// or an empty slice.
+func registerSliceType[E any](parse func(string) (E, error)) {
+	RegisterTypeHandler[[]E](&typeHandlerImpl{
+		makeFlag: func(desc Description) cli.Flag {
+			f := &sliceFlag[E]{
+				GenericFlag: &cli.GenericFlag{
+					Name:  desc.Name,
+					Usage: desc.Usage,
+				},
+				defaultValue: tryCast[[]E](desc.Default),
+				parse:        parse,
+				separator:    desc.Separator,
+			}
+			f.Value = f.newValue()
+			return f
+		},
+		getFlag: func(c *cli.Context, name string) any {
+			value, isSlice := c.Generic(name).(*sliceValue[E])
+			if !isSlice || value.values == nil {
+				return []E{}
+			}
+			return value.values
+		},
+	})
+}
@@ -0,0 +1,73 @@
+package flags
+
+import (
+	"encoding"
+	"flag"
+	"fmt"
+	"github.com/urfave/cli/v2"
+	"reflect"
+)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+var flagValueType = reflect.TypeOf((*flag.Value)(nil)).Elem()
+
+func isTextType(typ reflect.Type) bool {
+	return typ.Implements(textUnmarshalerType) || typ.Implements(flagValueType)
+}
+
+// textValueType returns the type of the values held by a flag of type T, if T is a text type.
+//
+// Text types are types whose pointers implement encoding.TextUnmarshaler or flag.Value.
+// If T is a pointer to a text type, the flag is optional.
+func textValueType[T any]() (valueType reflect.Type, isOptional bool, isText bool) {
+	typ := reflect.TypeOf(*new(T))
+	if isTextType(reflect.PointerTo(typ)) {
+		return typ, false, true
+	}
+	if typ.Kind() == reflect.Pointer && isTextType(typ) {
+		return typ.Elem(), true, true
+	}
+	return nil, false, false
+}
+
+// makeTextFlag creates a flag for a text type.
+//
+// The flag holds pointers to the values, as the interfaces are implemented by the pointers.
+func makeTextFlag(desc Description, valueType reflect.Type, isOptional bool) cli.Flag {
+	parse := func(s string) (any, error) {
+		pointer := reflect.New(valueType)
+		if unmarshaler, isUnmarshaler := pointer.Interface().(encoding.TextUnmarshaler); isUnmarshaler {
+			return pointer.Interface(), unmarshaler.UnmarshalText([]byte(s))
+		}
+		return pointer.Interface(), pointer.Interface().(flag.Value).Set(s)
+	}
+	format := func(value any) string {
+		if value == nil {
+			return ""
+		}
+		if marshaler, isMarshaler := value.(encoding.TextMarshaler); isMarshaler {
+			text, err := marshaler.MarshalText()
+			if err == nil {
+				return string(text)
+			}
+		}
+		return fmt.Sprint(value)
+	}
+
+	if isOptional {
+		desc.Default = nil
+	}
+	if text, isString := desc.Default.(string); isString && !reflect.TypeOf(text).AssignableTo(valueType) {
+		// Like the values passed on the command line, default values written as strings are parsed.
+		value, err := parse(text)
+		if err != nil {
+			panic(fmt.Sprintf("invalid default value %q for flag %s: %s", text, desc.Name, err))
+		}
+		desc.Default = value
+	} else if desc.Default != nil {
+		pointer := reflect.New(valueType)
+		pointer.Elem().Set(reflect.ValueOf(desc.Default))
+		desc.Default = pointer.Interface()
+	}
+	return newParsedFlag(desc, !isOptional && desc.Default == nil, parse, format)
+}
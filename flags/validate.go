@@ -0,0 +1,171 @@
+package flags
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// validator is a constraint on the values of a flag.
+type validator struct {
+	// description describes the constraint in the help, and is empty for constraints we can't describe.
+	description string
+	// check checks the value of a flag, as returned by GetFlag.
+	check func(value any) error
+}
+
+func addValidator(description string, check func(value any) error) Option {
+	return func(desc *Description) {
+		desc.Validators = append(desc.Validators, validator{description: description, check: check})
+	}
+}
+
+// deref dereferences pointer values, reporting false for nil pointers, which are never checked.
+func deref(value any) (reflect.Value, bool) {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, v.IsValid()
+}
+
+type number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// Range limits the values of a numeric flag to the given range, inclusive.
+func Range[T number](min T, max T) Option {
+	return addValidator(fmt.Sprintf("range: %v-%v", min, max), func(value any) error {
+		v, ok := deref(value)
+		if !ok {
+			return nil
+		}
+		n := v.Convert(reflect.TypeOf(min)).Interface().(T)
+		if n < min || n > max {
+			return errors.Errorf("must be between %v and %v", min, max)
+		}
+		return nil
+	})
+}
+
+// MinLen sets the minimal length of a string, slice or map flag.
+func MinLen(minLen int) Option {
+	return addValidator(fmt.Sprintf("min length: %d", minLen), func(value any) error {
+		v, ok := deref(value)
+		if !ok {
+			return nil
+		}
+		if v.Len() < minLen {
+			return errors.Errorf("must have a length of at least %d", minLen)
+		}
+		return nil
+	})
+}
+
+// Pattern makes a string flag only accept values matching a regular expression.
+func Pattern(pattern string) Option {
+	re := regexp.MustCompile(pattern)
+	return addValidator("pattern: "+pattern, func(value any) error {
+		v, ok := deref(value)
+		if !ok {
+			return nil
+		}
+		if !re.MatchString(v.String()) {
+			return errors.Errorf("must match %s", pattern)
+		}
+		return nil
+	})
+}
+
+// OneOf limits the values of a flag to the given ones.
+func OneOf[T comparable](values ...T) Option {
+	texts := make([]string, len(values))
+	for i, value := range values {
+		texts[i] = fmt.Sprint(value)
+	}
+	validValues := strings.Join(texts, ", ")
+	return addValidator("one of: "+validValues, func(value any) error {
+		v, ok := deref(value)
+		if !ok {
+			return nil
+		}
+		actual := v.Convert(reflect.TypeOf(*new(T))).Interface().(T)
+		for _, valid := range values {
+			if actual == valid {
+				return nil
+			}
+		}
+		return errors.Errorf("must be one of: %s", validValues)
+	})
+}
+
+// Validate checks the values of a flag using a function.
+//
+// The function gets the value of the flag as the action function does, so for an optional flag
+// it gets a pointer, which is nil if the flag is not set.
+func Validate[T any](fn func(T) error) Option {
+	return addValidator("", func(value any) error {
+		return fn(value.(T))
+	})
+}
+
+// describeValidators adds the descriptions of the validators to the usage of a flag.
+func describeValidators(desc Description) string {
+	usage := desc.Usage
+	for _, v := range desc.Validators {
+		if v.description != "" {
+			usage += " (" + v.description + ")"
+		}
+	}
+	return strings.TrimSpace(usage)
+}
+
+// formatValue formats a value for error messages, quoting strings.
+func formatValue(value any) string {
+	v, ok := deref(value)
+	if !ok {
+		return "nil"
+	}
+	if v.Kind() == reflect.String {
+		return fmt.Sprintf("%q", v.String())
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// ValidateFlag checks the value of a flag against all of its validators, returning all the failures.
+func ValidateFlag[T any](c *cli.Context, name string, options ...Option) []error {
+	var desc Description
+	for _, option := range options {
+		option(&desc)
+	}
+	value := GetFlag[T](c, name)
+	var failures []error
+	for _, v := range desc.Validators {
+		if err := v.check(value); err != nil {
+			failures = append(failures, errors.Errorf("invalid value %s for flag --%s: %s", formatValue(value), name, err))
+		}
+	}
+	return failures
+}
+
+// ValidateFlags reports the failures of ValidateFlag calls together, as a single usage error.
+func ValidateFlags(c *cli.Context, failures ...[]error) error {
+	var messages []string
+	for _, flagFailures := range failures {
+		for _, failure := range flagFailures {
+			messages = append(messages, failure.Error())
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return usageError(c, errors.New(strings.Join(messages, "\n")))
+}
@@ -3,13 +3,15 @@ module github.com/tmr232/goat
 go 1.18
 
 require (
+	github.com/BurntSushi/toml v1.1.0
+	github.com/approvals/go-approval-tests v0.0.0-20220530063708-32d5677069bd
 	github.com/pkg/errors v0.9.1
 	github.com/urfave/cli/v2 v2.19.2
 	golang.org/x/tools v0.1.12
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/approvals/go-approval-tests v0.0.0-20220530063708-32d5677069bd // indirect
 	github.com/cpuguy83/go-md2man/v2 v2.0.2 // indirect
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
 	github.com/xrash/smetrics v0.0.0-20201216005158-039620a65673 // indirect
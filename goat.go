@@ -1,10 +1,16 @@
 package goat
 
 import (
+	"flag"
+	"fmt"
+	"github.com/tmr232/goat/flags"
 	"github.com/urfave/cli/v2"
+	"io"
 	"log"
 	"os"
 	"reflect"
+	"strconv"
+	"strings"
 )
 
 type RunConfig struct {
@@ -16,16 +22,49 @@ type RunConfig struct {
 	CtxFlagBuilder func(c *cli.Context) map[string]any
 	Name           string
 	Usage          string
+	// Args describes the positional arguments, in order.
+	Args []flags.Arg
+	// Hidden commands are left out of the help.
+	Hidden bool
+	// Deprecated is a message shown when a deprecated command is used, and empty for other commands.
+	Deprecated string
+	// Category is the heading the command is listed under in the help.
+	Category string
 }
 
 var runConfigByFunction map[reflect.Value]RunConfig
 var functionByCliActionFunc map[reflect.Value]reflect.Value
 
+// configByCommand holds the RunConfig of every command created using Command, for documentation.
+var configByCommand = make(map[*cli.Command]RunConfig)
+
 func init() {
 	runConfigByFunction = make(map[reflect.Value]RunConfig)
 	functionByCliActionFunc = make(map[reflect.Value]reflect.Value)
 }
 
+// subcommandHelpTemplates holds the custom help templates of the commands with subcommands created using Command.
+var subcommandHelpTemplates = make(map[string]bool)
+
+// useSubcommandHelpTemplates makes cli use the custom help templates of the commands with subcommands,
+// returning a function that restores cli.HelpPrinter.
+//
+// When showing the help of a command with subcommands, cli ignores the custom help template
+// of the command and always uses cli.SubcommandHelpTemplate. As only cli.HelpPrinter can change that,
+// it is replaced while a goat app runs, and only changes the help of goat's own commands.
+func useSubcommandHelpTemplates() (restore func()) {
+	printHelp := cli.HelpPrinter
+	cli.HelpPrinter = func(w io.Writer, template string, data interface{}) {
+		if app, isApp := data.(*cli.App); isApp && template == cli.SubcommandHelpTemplate && subcommandHelpTemplates[app.CustomAppHelpTemplate] {
+			template = app.CustomAppHelpTemplate
+		}
+		printHelp(w, template, data)
+	}
+	return func() {
+		cli.HelpPrinter = printHelp
+	}
+}
+
 // Register registers a RunConfig generated from a function.
 //
 // This is only used in generated code.
@@ -35,16 +74,114 @@ func Register(app any, config RunConfig) {
 	functionByCliActionFunc[reflect.ValueOf(config.Action)] = appValue
 }
 
-func RunWithArgsE(f any, args []string) error {
-	config := runConfigByFunction[reflect.ValueOf(f)]
+// argsUsage formats the positional arguments for the usage line, as in `SRC [DST] [FILES...]`.
+func argsUsage(args []flags.Arg) string {
+	names := make([]string, len(args))
+	for i, arg := range args {
+		names[i] = arg.Name
+		if arg.Variadic {
+			names[i] += "..."
+		}
+		if arg.Optional {
+			names[i] = "[" + names[i] + "]"
+		}
+	}
+	return strings.Join(names, " ")
+}
 
-	app := &cli.App{
-		Flags:  config.Flags,
-		Action: config.Action,
-		Name:   config.Name,
-		Usage:  config.Usage,
+// argsHelpTemplate adds a description of the positional arguments to a help template,
+// right before the flags.
+//
+// If none of the arguments has a usage or a default value, the usage line is enough,
+// and the template is returned as is.
+func argsHelpTemplate(template string, args []flags.Arg) string {
+	isDescribed := false
+	for _, arg := range args {
+		isDescribed = isDescribed || arg.Usage != "" || arg.DefaultText != ""
+	}
+	if !isDescribed {
+		return template
+	}
+	section := "\n\nARGUMENTS:"
+	for _, arg := range args {
+		description := arg.Usage
+		if arg.DefaultText != "" {
+			description = strings.TrimSpace(description + " (default: " + arg.DefaultText + ")")
+		}
+		line := arg.Name
+		if description != "" {
+			line += "\t" + description
+		}
+		// The text is quoted, so that it is not parsed as part of the template.
+		section += "\n   {{" + strconv.Quote(line) + "}}"
+	}
+	return strings.Replace(template, "{{if .VisibleFlagCategories}}", section+"{{if .VisibleFlagCategories}}", 1)
+}
+
+// flagCategories returns the categories of the flags, in the order they first appear.
+func flagCategories(cliFlags []cli.Flag) []string {
+	var categories []string
+	seen := make(map[string]bool)
+	for _, flag := range cliFlags {
+		categorizable, isCategorizable := flag.(cli.CategorizableFlag)
+		if !isCategorizable || categorizable.GetCategory() == "" || seen[categorizable.GetCategory()] {
+			continue
+		}
+		seen[categorizable.GetCategory()] = true
+		categories = append(categories, categorizable.GetCategory())
+	}
+	return categories
+}
+
+// flagsHelpTemplate lists categorized flags under their categories in a help template.
+//
+// We don't use the categories support in cli, as it leaves out the uncategorized flags
+// and sorts the flags by name. Here, uncategorized flags come first, and the rest are
+// listed in the order they are declared in.
+func flagsHelpTemplate(template string, cliFlags []cli.Flag) string {
+	categories := flagCategories(cliFlags)
+	if len(categories) == 0 {
+		return template
+	}
+	section := `{{range .VisibleFlags}}{{if not .GetCategory}}
+   {{wrap .String 6}}{{end}}{{end}}`
+	for _, category := range categories {
+		quoted := strconv.Quote(category)
+		section += "\n\n   {{" + quoted + "}}\n" + `{{range .VisibleFlags}}{{if eq .GetCategory ` + quoted + `}}
+   {{wrap .String 6}}{{end}}{{end}}`
 	}
+	template = strings.Replace(template, `{{template "visibleFlagCategoryTemplate" .}}`, section, 1)
+	return strings.Replace(template, `{{template "visibleFlagTemplate" .}}`, section, 1)
+}
+
+// helpTemplate adds the parts of the help that cli doesn't handle to a help template.
+func helpTemplate(template string, config RunConfig) string {
+	template = argsHelpTemplate(template, config.Args)
+	return flagsHelpTemplate(template, config.Flags)
+}
+
+// warnDeprecated returns a cli.BeforeFunc warning about the use of a deprecated command or deprecated flags.
+func warnDeprecated(config RunConfig) cli.BeforeFunc {
+	return func(c *cli.Context) error {
+		if config.Deprecated != "" {
+			fmt.Fprintf(c.App.ErrWriter, "Warning: command %s is deprecated: %s\n", config.Name, config.Deprecated)
+		}
+		flags.WarnDeprecated(c, config.Flags)
+		return nil
+	}
+}
+
+// usage returns the usage of a command, noting if it is deprecated.
+func usage(config RunConfig) string {
+	if config.Deprecated == "" {
+		return config.Usage
+	}
+	return strings.TrimSpace(config.Usage + " (deprecated: " + config.Deprecated + ")")
+}
 
+func RunWithArgsE(f any, args []string) error {
+	app := FuncToApp(f)
+	flags.ResetFlags(app.Flags)
 	return app.Run(args)
 }
 
@@ -52,10 +189,17 @@ func FuncToApp(f any) *cli.App {
 	config := runConfigByFunction[reflect.ValueOf(f)]
 
 	app := &cli.App{
-		Flags:  config.Flags,
-		Action: config.Action,
-		Name:   config.Name,
-		Usage:  config.Usage,
+		Flags:     config.Flags,
+		Action:    config.Action,
+		Name:      config.Name,
+		Usage:     usage(config),
+		ArgsUsage: argsUsage(config.Args),
+		Before:    warnDeprecated(config),
+		// Allows combining single-letter flags, as in `-vvv`.
+		UseShortOptionHandling: flags.HasShortFlags(config.Flags),
+	}
+	if template := helpTemplate(cli.AppHelpTemplate, config); template != cli.AppHelpTemplate {
+		app.CustomAppHelpTemplate = template
 	}
 	return app
 }
@@ -98,6 +242,12 @@ func (g *GoatGroup) Usage(usage string) *GoatGroup {
 	return g
 }
 
+// Category sets the heading the group is listed under in the help.
+func (g *GoatGroup) Category(category string) *GoatGroup {
+	g.command.Category = category
+	return g
+}
+
 func PartsToCommands(parts []AppPart) []*cli.Command {
 	commands := make([]*cli.Command, len(parts))
 	for i, part := range parts {
@@ -109,13 +259,31 @@ func PartsToCommands(parts []AppPart) []*cli.Command {
 func Command(f any, subcommands ...AppPart) *GoatCommand {
 	config := runConfigByFunction[reflect.ValueOf(f)]
 
-	return &GoatCommand{&cli.Command{
+	command := &cli.Command{
 		Flags:       config.Flags,
 		Action:      config.Action,
 		Name:        config.Name,
-		Usage:       config.Usage,
+		Usage:       usage(config),
+		ArgsUsage:   argsUsage(config.Args),
 		Subcommands: PartsToCommands(subcommands),
-	}}
+		Hidden:      config.Hidden,
+		Category:    config.Category,
+		Before:      warnDeprecated(config),
+		// Allows combining single-letter flags, as in `-vvv`.
+		UseShortOptionHandling: flags.HasShortFlags(config.Flags),
+	}
+	baseTemplate := cli.CommandHelpTemplate
+	if len(subcommands) != 0 {
+		baseTemplate = cli.SubcommandHelpTemplate
+	}
+	if template := helpTemplate(baseTemplate, config); template != baseTemplate {
+		command.CustomHelpTemplate = template
+		if len(subcommands) != 0 {
+			subcommandHelpTemplates[template] = true
+		}
+	}
+	configByCommand[command] = config
+	return &GoatCommand{command}
 }
 
 func Group(name string, subcommands ...AppPart) *GoatGroup {
@@ -125,30 +293,165 @@ func Group(name string, subcommands ...AppPart) *GoatGroup {
 	}}
 }
 
-type Application struct{ *cli.App }
+type Application struct {
+	*cli.App
+	config *flags.ConfigFile
+}
 
 func (app Application) RunWithArgsE(args []string) error {
+	defer useSubcommandHelpTemplates()()
+	flags.ResetFlags(app.Flags)
+	resetCommandFlags(app.Commands)
 	return app.App.Run(args)
 }
 
 func (app Application) RunE() error {
-	return app.App.Run(os.Args)
+	return app.RunWithArgsE(os.Args)
 }
 func (app Application) Run() {
-	err := app.App.Run(os.Args)
+	err := app.RunE()
 	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+func resetCommandFlags(commands []*cli.Command) {
+	for _, command := range commands {
+		flags.ResetFlags(command.Flags)
+		resetCommandFlags(command.Subcommands)
+	}
+}
+
+// EnvPrefix binds every flag of the app that has no environment variables to a variable
+// derived from the prefix and the flag's name, as in `APP_DB_HOST` for `--db-host`.
+//
+// The flags are cloned before they are bound, as they are shared with the other apps and commands
+// created from the same functions.
+func (app Application) EnvPrefix(prefix string) Application {
+	app.Flags = flags.CloneFlags(app.Flags)
+	flags.BindEnvPrefix(app.Flags, prefix)
+	bindCommandsEnvPrefix(app.Commands, prefix)
+	return app
+}
+
+func bindCommandsEnvPrefix(commands []*cli.Command, prefix string) {
+	for _, command := range commands {
+		command.Flags = flags.CloneFlags(command.Flags)
+		flags.BindEnvPrefix(command.Flags, prefix)
+		bindCommandsEnvPrefix(command.Subcommands, prefix)
+	}
+}
+
+const configFlagName = "config"
+const printConfigFlagName = "print-config"
+
+// ConfigFile takes the values of flags that are not passed on the command line, or by
+// environment variables, from a config file.
+//
+// The file is given using the --config flag, and read from the path if the flag is not passed.
+// The keys of the file mirror the command tree, so the flags of `tool db migrate` are read from
+// `db.migrate`. The --print-config flag prints the effective values of the flags instead of
+// running the command.
+//
+// Like with EnvPrefix, the flags are cloned before they are bound.
+func (app Application) ConfigFile(path string) Application {
+	app.config = flags.NewConfigFile(path)
+	app.Flags = flags.CloneFlags(app.Flags)
+	root := configLevel{flags: app.Flags}
+	bindCommandsConfig(app.config, app.Commands, []configLevel{root})
+	app.Flags = append(app.config.Bind(app.Flags, nil),
+		app.config.PathFlag(configFlagName, "Read flag values from `FILE`"),
+		&cli.BoolFlag{
+			Name:  printConfigFlagName,
+			Usage: "Print the effective flag values instead of running the command",
+		},
+	)
+	return app
+}
+
+// configLevel holds the flags of a command, and the keys their values are nested under in a config file.
+type configLevel struct {
+	keys  []string
+	flags []cli.Flag
+}
+
+func bindCommandsConfig(config *flags.ConfigFile, commands []*cli.Command, parents []configLevel) {
+	parentKeys := parents[len(parents)-1].keys
+	for _, command := range commands {
+		command.Flags = flags.CloneFlags(command.Flags)
+		level := configLevel{
+			keys:  append(parentKeys[:len(parentKeys):len(parentKeys)], command.Name),
+			flags: command.Flags,
+		}
+		levels := append(parents[:len(parents):len(parents)], level)
+		command.Flags = config.Bind(command.Flags, level.keys)
+		if command.Action != nil {
+			command.Action = printConfig(config, levels, command.Action)
+		}
+		bindCommandsConfig(config, command.Subcommands, levels)
+	}
+}
+
+// printConfig wraps the action of a command, printing the effective values of the command's flags,
+// and the flags of its parents, instead of running it when --print-config is passed.
+func printConfig(config *flags.ConfigFile, levels []configLevel, action cli.ActionFunc) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		if !c.Bool(printConfigFlagName) {
+			return action(c)
+		}
+		values := make(map[string]any)
+		for _, level := range levels {
+			if len(level.flags) == 0 {
+				continue
+			}
+			table := values
+			for _, key := range level.keys {
+				nested, exists := table[key].(map[string]any)
+				if !exists {
+					nested = make(map[string]any)
+					table[key] = nested
+				}
+				table = nested
+			}
+			for _, f := range level.flags {
+				name := f.Names()[0]
+				value, _ := c.Generic(name).(flag.Value)
+				if configValue := flags.ConfigValue(value); configValue != nil {
+					table[name] = configValue
+				}
+			}
+		}
+		return config.Encode(c.App.Writer, values)
+	}
+}
+
 func App(name string, commands ...AppPart) Application {
+	cliCommands := PartsToCommands(commands)
 	return Application{
 		App: &cli.App{
-			Name:     name,
-			Commands: PartsToCommands(commands),
+			Name:                   name,
+			Commands:               cliCommands,
+			UseShortOptionHandling: needsShortOptionHandling(cliCommands),
 		},
 	}
 }
 
+// needsShortOptionHandling returns true if any of the commands with subcommands combines single-letter flags.
+//
+// cli runs the commands with subcommands as apps, using the short option handling of the parent app
+// instead of the command's own, so the app has to enable it for them.
+func needsShortOptionHandling(commands []*cli.Command) bool {
+	for _, command := range commands {
+		if len(command.Subcommands) == 0 {
+			continue
+		}
+		if command.UseShortOptionHandling || needsShortOptionHandling(command.Subcommands) {
+			return true
+		}
+	}
+	return false
+}
+
 // Flag creates a flag-descriptor to be used during code-generation to describe the flag.
 //
 // Should be used with the FluentFlag.Name, FluentFlag.Usage and FluentFlag.Default methods.
@@ -177,11 +480,184 @@ func (f FluentFlag) Usage(string) FluentFlag {
 
 // Default sets the default value for a flag.
 //
-// Must be called with the same type as the flag.
+// Must be called with the same type as the flag. For time.Time flags and flags of
+// text types, it can also be called with a string, parsed like a value on the command line.
 func (f FluentFlag) Default(any) FluentFlag {
 	return FluentFlag{}
 }
 
+// Layout sets the layout used for parsing a time.Time flag.
+//
+// Defaults to time.RFC3339.
+func (f FluentFlag) Layout(string) FluentFlag {
+	return FluentFlag{}
+}
+
+// Separator sets a separator for splitting a single value of a slice or map flag into multiple values.
+//
+// By default, every value of a slice flag is a single item, and every value of a map flag
+// is a single KEY=VALUE pair.
+func (f FluentFlag) Separator(string) FluentFlag {
+	return FluentFlag{}
+}
+
+// Alias adds alternative names for a flag.
+//
+// The names must be constants, and must not be used by any other flag of the command.
+func (f FluentFlag) Alias(...string) FluentFlag {
+	return FluentFlag{}
+}
+
+// Short adds a single-letter alias for a flag, as in `-n` for `--count`.
+func (f FluentFlag) Short(rune) FluentFlag {
+	return FluentFlag{}
+}
+
+// EnvVar binds a flag to environment variables, used when the flag is not passed.
+//
+// The first variable that is set is used, and takes precedence over the default value.
+// A required flag is satisfied by any of its variables.
+func (f FluentFlag) EnvVar(...string) FluentFlag {
+	return FluentFlag{}
+}
+
+// Hidden leaves a flag out of the help.
+func (f FluentFlag) Hidden() FluentFlag {
+	return FluentFlag{}
+}
+
+// Deprecated marks a flag as deprecated. The flag keeps working, but using it prints
+// a warning with the given message to the app's ErrWriter.
+//
+// Example:
+// 	Flag(oldName).Deprecated("use --new-name")
+func (f FluentFlag) Deprecated(string) FluentFlag {
+	return FluentFlag{}
+}
+
+// Range limits the values of a numeric flag to a range, inclusive.
+//
+// The help describes the range, and values outside of it are rejected before calling the function.
+func (f FluentFlag) Range(min, max any) FluentFlag {
+	return FluentFlag{}
+}
+
+// MinLen sets the minimal length of a string, slice or map flag.
+func (f FluentFlag) MinLen(int) FluentFlag {
+	return FluentFlag{}
+}
+
+// Pattern makes a string flag only accept values matching a regular expression.
+func (f FluentFlag) Pattern(string) FluentFlag {
+	return FluentFlag{}
+}
+
+// OneOf limits the values of a flag to the given ones.
+//
+// Must be called with values of the same type as the flag.
+func (f FluentFlag) OneOf(...any) FluentFlag {
+	return FluentFlag{}
+}
+
+// Validate checks the values of a flag using a function.
+//
+// The function must be a func(T) error, where T is the type of the flag.
+// All the validators of all the flags are checked before calling the function,
+// and all the failures are reported together.
+func (f FluentFlag) Validate(any) FluentFlag {
+	return FluentFlag{}
+}
+
+// Category sets the heading a flag is listed under in the help.
+//
+// Flags without a category are listed first, and the categories are listed
+// in the order they first appear in.
+func (f FluentFlag) Category(string) FluentFlag {
+	return FluentFlag{}
+}
+
+// Count makes an int flag count the times it is passed, as in `-vvv` for verbosity.
+//
+// The flag takes no value. Unless the flag has aliases, the first letter of its name
+// is added as an alias, if no other flag uses it.
+// Default sets the starting count.
+func (f FluentFlag) Count() FluentFlag {
+	return FluentFlag{}
+}
+
+// File marks a string flag as taking file paths, so that shells complete its values with file paths.
+func (f FluentFlag) File() FluentFlag {
+	return FluentFlag{}
+}
+
+// Complete sets a function providing the candidates for completing the flag's value,
+// like the names of the available clusters.
+//
+// The function must be a named func(ctx *goat.Context, prefix string) []string.
+// It is called by the completion scripts, through the hidden `__complete` command.
+func (f FluentFlag) Complete(any) FluentFlag {
+	return FluentFlag{}
+}
+
+// Exclusive declares that at most one of the given flags can be used.
+//
+// It is used during code-generation, and the generated code checks it before calling the function.
+// The arguments must be parameters of the function the call is in.
+//
+// Example:
+// 	func f(json, yaml bool) {
+//		Exclusive(json, yaml)
+//	}
+func Exclusive(...any) {}
+
+// Together declares that the given flags can only be used together.
+//
+// It is used during code-generation, and the generated code checks it before calling the function.
+// The arguments must be parameters of the function the call is in.
+//
+// Example:
+// 	func f(user, password *string) {
+//		Together(user, password)
+//	}
+func Together(...any) {}
+
+// Arg creates an argument-descriptor, making a parameter a positional argument instead of a flag.
+//
+// Positional arguments are taken in the order of the parameters. Arguments with a pointer type
+// or a default value are optional, and must come after the required ones.
+//
+// Should be used with the FluentArg.Name, FluentArg.Usage and FluentArg.Default methods.
+//
+// Example:
+// 	func copyFile(src, dst string) {
+//		Arg(src).Name("SRC")
+//		Arg(dst).Name("DST")
+//	}
+func Arg(any) FluentArg {
+	return FluentArg{}
+}
+
+type FluentArg struct{}
+
+// Name sets the name of an argument, as shown in the help.
+//
+// Defaults to the upper-cased parameter name.
+func (a FluentArg) Name(string) FluentArg {
+	return FluentArg{}
+}
+
+// Usage sets the usage of an argument.
+func (a FluentArg) Usage(string) FluentArg {
+	return FluentArg{}
+}
+
+// Default sets the default value for an argument, making it optional.
+//
+// Must be called with the same type as the argument.
+func (a FluentArg) Default(any) FluentArg {
+	return FluentArg{}
+}
+
 type FluentSelf struct{}
 
 // Self begins a description-chain for the current function.
@@ -198,3 +674,19 @@ func (s FluentSelf) Name(string) FluentSelf {
 func (s FluentSelf) Usage(string) FluentSelf {
 	return FluentSelf{}
 }
+
+// Hidden leaves the current function out of the list of commands in the help.
+func (s FluentSelf) Hidden() FluentSelf {
+	return FluentSelf{}
+}
+
+// Deprecated marks the current function as deprecated. The command keeps working,
+// but using it prints a warning with the given message to the app's ErrWriter.
+func (s FluentSelf) Deprecated(string) FluentSelf {
+	return FluentSelf{}
+}
+
+// Category sets the heading the current function is listed under in the list of commands in the help.
+func (s FluentSelf) Category(string) FluentSelf {
+	return FluentSelf{}
+}
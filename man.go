@@ -0,0 +1,256 @@
+package goat
+
+import (
+	"fmt"
+	"github.com/tmr232/goat/flags"
+	"github.com/urfave/cli/v2"
+	"io"
+	"strings"
+)
+
+// ManPages renders a roff man page for the app, and for each of its visible commands.
+//
+// The pages are taken from the names, usages and flags of the commands. For commands created
+// by goater, these are the doc comments and the flag descriptions of the functions.
+func (app Application) ManPages() []DocPage {
+	return manPages(app.App)
+}
+
+// WriteManPages writes the man pages of the app to a directory, creating it if needed.
+func (app Application) WriteManPages(dir string) error {
+	return writeManPages(app.App, dir)
+}
+
+// ManCommand adds a hidden `man [DIR]` command, writing the man pages of the app to DIR,
+// or printing the page of the app if DIR is not given.
+func (app Application) ManCommand() Application {
+	app.Commands = append(app.Commands, &cli.Command{
+		Name:      "man",
+		Usage:     "Write the man pages",
+		ArgsUsage: "[DIR]",
+		Hidden:    true,
+		Action: func(c *cli.Context) error {
+			if err := flags.CheckArgCount(c, 0, 1); err != nil {
+				return err
+			}
+			if c.NArg() == 1 {
+				return writeManPages(c.App, c.Args().First())
+			}
+			_, err := io.WriteString(c.App.Writer, manPages(c.App)[0].Content)
+			return err
+		},
+	})
+	return app
+}
+
+func manPages(app *cli.App) []DocPage {
+	var pages []DocPage
+	appDoc(app).walk(func(doc *commandDoc) {
+		pages = append(pages, DocPage{Name: doc.pageName() + ".1", Content: manPage(app, doc)})
+	})
+	return pages
+}
+
+func writeManPages(app *cli.App, dir string) error {
+	return writeDocPages(manPages(app), dir)
+}
+
+func manPage(app *cli.App, doc *commandDoc) string {
+	var b strings.Builder
+	// The page is left undated, so that it is the same every time it is generated.
+	fmt.Fprintf(&b, ".TH %s 1 \"\" %s \"User Commands\"\n",
+		roffQuote(strings.ToUpper(doc.pageName())), roffQuote(strings.TrimSpace(app.Name+" "+app.Version)))
+
+	b.WriteString(".SH NAME\n")
+	b.WriteString(roffName(doc.pageName()))
+	if summary := doc.summary(); summary != "" {
+		b.WriteString(` \- ` + roffText(summary))
+	}
+	b.WriteString("\n")
+
+	b.WriteString(".SH SYNOPSIS\n")
+	b.WriteString(`\fB` + roffName(doc.name()) + `\fR`)
+	if len(doc.visibleFlags()) > 0 {
+		b.WriteString(` [\fIoptions\fR]`)
+	}
+	if len(doc.visibleCommands()) > 0 {
+		b.WriteString(` \fIcommand\fR`)
+	} else if doc.argsUsage != "" {
+		b.WriteString(` \fI` + roffText(doc.argsUsage) + `\fR`)
+	}
+	b.WriteString("\n")
+
+	if paragraphs := doc.paragraphs(); len(paragraphs) > 0 || doc.deprecated != "" {
+		b.WriteString(".SH DESCRIPTION\n")
+		for i, paragraph := range paragraphs {
+			if i > 0 {
+				b.WriteString(".PP\n")
+			}
+			b.WriteString(roffText(paragraph) + "\n")
+		}
+		if doc.deprecated != "" {
+			if len(paragraphs) > 0 {
+				b.WriteString(".PP\n")
+			}
+			b.WriteString(`\fBDeprecated:\fR ` + roffText(doc.deprecated) + "\n")
+		}
+	}
+
+	if args := doc.describedArgs(); len(args) > 0 {
+		b.WriteString(".SH ARGUMENTS\n")
+		for _, arg := range args {
+			b.WriteString(".TP\n")
+			b.WriteString(`\fI` + roffText(arg.Name) + `\fR` + "\n")
+			writeRoffLine(&b, roffText(argDescription(arg)))
+		}
+	}
+
+	if visibleFlags := doc.visibleFlags(); len(visibleFlags) > 0 {
+		b.WriteString(".SH OPTIONS\n")
+		category := ""
+		for _, f := range visibleFlags {
+			if f.category != category {
+				category = f.category
+				b.WriteString(".SS " + roffQuote(category) + "\n")
+			}
+			b.WriteString(".TP\n")
+			b.WriteString(manFlagNames(f) + "\n")
+			writeRoffLine(&b, roffText(flagDescription(f)))
+		}
+	}
+
+	if commands := doc.visibleCommands(); len(commands) > 0 {
+		b.WriteString(".SH COMMANDS\n")
+		for _, group := range groupCommands(commands) {
+			if group.category != "" {
+				b.WriteString(".SS " + roffQuote(group.category) + "\n")
+			}
+			for _, command := range group.commands {
+				b.WriteString(".TP\n")
+				b.WriteString(`\fB` + roffName(command.path[len(command.path)-1]) + `\fR` + "\n")
+				writeRoffLine(&b, roffText(command.summary()))
+			}
+		}
+	}
+
+	var related []string
+	if len(doc.path) > 1 {
+		related = append(related, strings.Join(doc.path[:len(doc.path)-1], "-"))
+	}
+	for _, command := range doc.visibleCommands() {
+		related = append(related, command.pageName())
+	}
+	if len(related) > 0 {
+		b.WriteString(".SH SEE ALSO\n")
+		for i, name := range related {
+			if i > 0 {
+				b.WriteString(",\n")
+			}
+			b.WriteString(`\fB` + roffName(name) + `\fR(1)`)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// commandGroup holds the commands listed under a category.
+type commandGroup struct {
+	category string
+	commands []*commandDoc
+}
+
+// groupCommands groups commands by their categories, with the uncategorized commands first,
+// and the rest in the order the categories first appear in.
+func groupCommands(commands []*commandDoc) []commandGroup {
+	groups := []commandGroup{{}}
+	indices := map[string]int{"": 0}
+	for _, command := range commands {
+		index, exists := indices[command.category]
+		if !exists {
+			index = len(groups)
+			indices[command.category] = index
+			groups = append(groups, commandGroup{category: command.category})
+		}
+		groups[index].commands = append(groups[index].commands, command)
+	}
+	if len(groups[0].commands) == 0 {
+		return groups[1:]
+	}
+	return groups
+}
+
+// manFlagNames formats the names of a flag, followed by its value, as in `--name, -n value`.
+func manFlagNames(f flagDoc) string {
+	names := make([]string, len(f.names))
+	for i, name := range f.names {
+		names[i] = `\fB` + roffName(flagName(name)) + `\fR`
+	}
+	formatted := strings.Join(names, ", ")
+	if f.takesValue {
+		formatted += ` \fI` + roffText(f.placeholder) + `\fR`
+	}
+	return formatted
+}
+
+// flagDescription describes a flag in a sentence or more, with its usage, default value,
+// environment variables, and whether it is required.
+func flagDescription(f flagDoc) string {
+	// The usage of flags with choices already lists them.
+	parts := []string{ensurePeriod(f.usage)}
+	if f.defaultText != "" && !f.required {
+		parts = append(parts, "Default: "+f.defaultText+".")
+	}
+	if len(f.envVars) > 0 {
+		parts = append(parts, "Environment: "+strings.Join(f.envVars, ", ")+".")
+	}
+	if f.required {
+		parts = append(parts, "Required.")
+	}
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
+
+// argDescription describes a positional argument in a sentence or more.
+func argDescription(arg flags.Arg) string {
+	parts := []string{ensurePeriod(arg.Usage)}
+	if arg.DefaultText != "" {
+		parts = append(parts, "Default: "+arg.DefaultText+".")
+	} else if arg.Optional {
+		parts = append(parts, "Optional.")
+	}
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
+
+func ensurePeriod(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.HasSuffix(text, ".") || strings.HasSuffix(text, ")") {
+		return text
+	}
+	return text + "."
+}
+
+// writeRoffLine writes a line of text, leaving out empty lines, which roff renders as blank lines.
+func writeRoffLine(b *strings.Builder, line string) {
+	if line != "" {
+		b.WriteString(line + "\n")
+	}
+}
+
+// roffText escapes text for roff, joining its lines.
+func roffText(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	text = strings.ReplaceAll(text, `\`, `\e`)
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = `\&` + text
+	}
+	return text
+}
+
+// roffName escapes names, such as commands and flags, so that their dashes are not hyphenated.
+func roffName(name string) string {
+	return strings.ReplaceAll(roffText(name), "-", `\-`)
+}
+
+// roffQuote quotes a macro argument.
+func roffQuote(text string) string {
+	return `"` + strings.ReplaceAll(roffText(text), `"`, `""`) + `"`
+}
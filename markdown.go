@@ -0,0 +1,151 @@
+package goat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MarkdownPages renders a markdown reference page for the app, and for each of its visible commands.
+//
+// Like the man pages, the pages are taken from the commands the app runs, so that they always
+// match the binary. For commands created by goater, they include the full doc comments of the functions.
+func (app Application) MarkdownPages() []DocPage {
+	var pages []DocPage
+	appDoc(app.App).walk(func(doc *commandDoc) {
+		pages = append(pages, DocPage{Name: doc.pageName() + ".md", Content: markdownPage(doc)})
+	})
+	return pages
+}
+
+// WriteDocs writes the markdown reference pages of the app to a directory, creating it if needed.
+func (app Application) WriteDocs(dir string) error {
+	return writeDocPages(app.MarkdownPages(), dir)
+}
+
+func markdownPage(doc *commandDoc) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", doc.name())
+	if len(doc.path) > 1 {
+		parent := &commandDoc{path: doc.path[:len(doc.path)-1]}
+		fmt.Fprintf(&b, "\nPart of [`%s`](%s.md).\n", parent.name(), parent.pageName())
+	}
+	for _, paragraph := range doc.paragraphs() {
+		b.WriteString("\n" + paragraph + "\n")
+	}
+	if doc.deprecated != "" {
+		b.WriteString("\n> **Deprecated:** " + doc.deprecated + "\n")
+	}
+
+	b.WriteString("\n## Synopsis\n\n```\n" + synopsis(doc) + "\n```\n")
+
+	if args := doc.describedArgs(); len(args) > 0 {
+		b.WriteString("\n## Arguments\n\n")
+		b.WriteString("| Argument | Description |\n")
+		b.WriteString("|----------|-------------|\n")
+		for _, arg := range args {
+			fmt.Fprintf(&b, "| `%s` | %s |\n", arg.Name, markdownCell(argDescription(arg)))
+		}
+	}
+
+	if visibleFlags := doc.visibleFlags(); len(visibleFlags) > 0 {
+		b.WriteString("\n## Flags\n")
+		category := ""
+		for i, f := range visibleFlags {
+			if i == 0 || f.category != category {
+				category = f.category
+				if category != "" {
+					b.WriteString("\n### " + category + "\n")
+				}
+				b.WriteString("\n| Flag | Type | Default | Environment | Required | Description |\n")
+				b.WriteString("|------|------|---------|-------------|----------|-------------|\n")
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n",
+				markdownFlagNames(f),
+				markdownCode(f.typ),
+				markdownCode(markdownDefault(f)),
+				markdownCodes(f.envVars),
+				markdownRequired(f),
+				markdownCell(f.usage),
+			)
+		}
+	}
+
+	if commands := doc.visibleCommands(); len(commands) > 0 {
+		b.WriteString("\n## Commands\n")
+		for _, group := range groupCommands(commands) {
+			if group.category != "" {
+				b.WriteString("\n### " + group.category + "\n")
+			}
+			b.WriteString("\n| Command | Description |\n")
+			b.WriteString("|---------|-------------|\n")
+			for _, command := range group.commands {
+				fmt.Fprintf(&b, "| [`%s`](%s.md) | %s |\n",
+					command.path[len(command.path)-1], command.pageName(), markdownCell(command.summary()))
+			}
+		}
+	}
+	return b.String()
+}
+
+// synopsis shows how a command is invoked, as in `tool deploy [options] SRC DST`.
+func synopsis(doc *commandDoc) string {
+	parts := []string{doc.name()}
+	if len(doc.visibleFlags()) > 0 {
+		parts = append(parts, "[options]")
+	}
+	if len(doc.visibleCommands()) > 0 {
+		parts = append(parts, "command")
+	} else if doc.argsUsage != "" {
+		parts = append(parts, doc.argsUsage)
+	}
+	return strings.Join(parts, " ")
+}
+
+// markdownFlagNames formats the names of a flag, followed by its value, as in `--name`, `-n` `value`.
+func markdownFlagNames(f flagDoc) string {
+	names := make([]string, len(f.names))
+	for i, name := range f.names {
+		names[i] = markdownCode(flagName(name))
+	}
+	formatted := strings.Join(names, ", ")
+	if f.takesValue {
+		formatted += " " + markdownCode(f.placeholder)
+	}
+	return formatted
+}
+
+// markdownDefault is the default value of a flag, if it is optional and takes a value.
+func markdownDefault(f flagDoc) string {
+	if f.required {
+		return ""
+	}
+	return f.defaultText
+}
+
+func markdownRequired(f flagDoc) string {
+	if f.required {
+		return "yes"
+	}
+	return "no"
+}
+
+// markdownCode formats text as code, leaving empty text out.
+func markdownCode(text string) string {
+	if text == "" {
+		return ""
+	}
+	return "`" + strings.ReplaceAll(text, "|", `\|`) + "`"
+}
+
+func markdownCodes(texts []string) string {
+	codes := make([]string, len(texts))
+	for i, text := range texts {
+		codes[i] = markdownCode(text)
+	}
+	return strings.Join(codes, ", ")
+}
+
+// markdownCell escapes text for a table cell, joining its lines.
+func markdownCell(text string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(text), " "), "|", `\|`)
+}
@@ -0,0 +1,170 @@
+package goat
+
+import (
+	"encoding/json"
+	"github.com/urfave/cli/v2"
+	"io"
+)
+
+// SpecVersion is the version of the schema of Spec.
+//
+// Fields may be added to the schema without changing the version, but it is increased
+// whenever fields are removed, renamed, or change their meaning.
+const SpecVersion = 1
+
+// Spec is a machine-readable description of an app, and of all of its commands and flags.
+//
+// It is serialized as JSON, for tools that need to read the CLI surface of an app.
+type Spec struct {
+	SpecVersion int           `json:"specVersion"`
+	Name        string        `json:"name"`
+	Version     string        `json:"version,omitempty"`
+	Usage       string        `json:"usage,omitempty"`
+	Flags       []FlagSpec    `json:"flags"`
+	Commands    []CommandSpec `json:"commands"`
+}
+
+// CommandSpec describes a command.
+type CommandSpec struct {
+	Name string `json:"name"`
+	// FullName is the name of the command, prefixed by the names of the app and the commands leading to it.
+	FullName string   `json:"fullName"`
+	Aliases  []string `json:"aliases,omitempty"`
+	// Usage is the full usage of the command, which is the doc comment for goater-documented functions.
+	Usage    string `json:"usage,omitempty"`
+	Category string `json:"category,omitempty"`
+	// Group is true for commands created using Group, which only hold subcommands.
+	Group      bool          `json:"group,omitempty"`
+	Hidden     bool          `json:"hidden,omitempty"`
+	Deprecated string        `json:"deprecated,omitempty"`
+	Args       []ArgSpec     `json:"args"`
+	Flags      []FlagSpec    `json:"flags"`
+	Commands   []CommandSpec `json:"commands"`
+}
+
+// ArgSpec describes a positional argument.
+type ArgSpec struct {
+	Name     string `json:"name"`
+	Usage    string `json:"usage,omitempty"`
+	Default  string `json:"default,omitempty"`
+	Optional bool   `json:"optional,omitempty"`
+	Variadic bool   `json:"variadic,omitempty"`
+}
+
+// FlagSpec describes a flag.
+type FlagSpec struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+	// Type is the Go type of the flag, as in `int` or `*time.Duration`.
+	Type  string `json:"type"`
+	Usage string `json:"usage,omitempty"`
+	// TakesValue is false for boolean flags, which are passed without a value.
+	TakesValue bool `json:"takesValue"`
+	// Default is the formatted default value of optional flags that take values.
+	Default    string   `json:"default,omitempty"`
+	Required   bool     `json:"required"`
+	EnvVars    []string `json:"envVars,omitempty"`
+	Choices    []string `json:"choices,omitempty"`
+	Category   string   `json:"category,omitempty"`
+	Hidden     bool     `json:"hidden,omitempty"`
+	Deprecated string   `json:"deprecated,omitempty"`
+}
+
+// Spec describes the app, and all of its commands and flags, including the hidden ones.
+func (app Application) Spec() Spec {
+	return appSpec(app.App)
+}
+
+// WriteSpec writes the spec of the app as indented JSON.
+func (app Application) WriteSpec(w io.Writer) error {
+	return writeSpec(app.App, w)
+}
+
+// SpecCommand adds a hidden `spec` command, printing the spec of the app as JSON.
+func (app Application) SpecCommand() Application {
+	app.Commands = append(app.Commands, &cli.Command{
+		Name:   "spec",
+		Usage:  "Print a JSON description of the commands and flags",
+		Hidden: true,
+		Action: func(c *cli.Context) error {
+			return writeSpec(c.App, c.App.Writer)
+		},
+	})
+	return app
+}
+
+func appSpec(app *cli.App) Spec {
+	doc := appDoc(app)
+	return Spec{
+		SpecVersion: SpecVersion,
+		Name:        app.Name,
+		Version:     app.Version,
+		Usage:       app.Usage,
+		Flags:       flagSpecs(doc.flags),
+		Commands:    commandSpecs(doc.commands),
+	}
+}
+
+func writeSpec(app *cli.App, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(appSpec(app))
+}
+
+func commandSpecs(docs []*commandDoc) []CommandSpec {
+	specs := []CommandSpec{}
+	for _, doc := range docs {
+		// The completion scripts call the internal __complete command, which is not a part of the app.
+		if doc.path[len(doc.path)-1] == completeCommandName {
+			continue
+		}
+		args := []ArgSpec{}
+		for _, arg := range doc.args {
+			args = append(args, ArgSpec{
+				Name:     arg.Name,
+				Usage:    arg.Usage,
+				Default:  arg.DefaultText,
+				Optional: arg.Optional,
+				Variadic: arg.Variadic,
+			})
+		}
+		specs = append(specs, CommandSpec{
+			Name:       doc.path[len(doc.path)-1],
+			FullName:   doc.name(),
+			Aliases:    doc.aliases,
+			Usage:      doc.usage,
+			Category:   doc.category,
+			Group:      doc.isGroup,
+			Hidden:     doc.hidden,
+			Deprecated: doc.deprecated,
+			Args:       args,
+			Flags:      flagSpecs(doc.flags),
+			Commands:   commandSpecs(doc.commands),
+		})
+	}
+	return specs
+}
+
+func flagSpecs(docs []flagDoc) []FlagSpec {
+	specs := []FlagSpec{}
+	for _, doc := range docs {
+		spec := FlagSpec{
+			Name:       doc.names[0],
+			Aliases:    doc.names[1:],
+			Type:       doc.typ,
+			Usage:      doc.usage,
+			TakesValue: doc.takesValue,
+			Required:   doc.required,
+			EnvVars:    doc.envVars,
+			Choices:    doc.choices,
+			Category:   doc.category,
+			Hidden:     doc.hidden,
+			Deprecated: doc.deprecated,
+		}
+		if !doc.required {
+			spec.Default = doc.defaultText
+		}
+		specs = append(specs, spec)
+	}
+	return specs
+}
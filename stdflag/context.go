@@ -0,0 +1,44 @@
+package stdflag
+
+import (
+	"errors"
+	"io"
+	"reflect"
+)
+
+type Context struct {
+	app Application
+	// values holds the flags of the command that runs.
+	values        map[string]any
+	flagsByAction map[reflect.Value]map[string]any
+}
+
+func (ctx *Context) GetFlag(f any, name string) (any, error) {
+	actionFlags, exists := ctx.flagsByAction[reflect.ValueOf(f)]
+	if !exists {
+		return nil, errors.New("Action wasn't triggered")
+	}
+	flag, exists := actionFlags[name]
+	if !exists {
+		return nil, errors.New("Flag doesn't exist")
+	}
+	return flag, nil
+}
+
+func (ctx *Context) GetWriter() io.Writer {
+	return ctx.app.Writer
+}
+
+func GetFlag[T any](ctx *Context, f any, name string) (T, error) {
+	anyVal, err := ctx.GetFlag(f, name)
+	if err != nil {
+		return *new(T), err
+	}
+	// We don't check for errors here because a bad cast here is a programmer error.
+	return anyVal.(T), nil
+}
+
+// FlagValue returns the value of a flag of the command that runs. It is only used in generated code.
+func FlagValue[T any](ctx *Context, name string) T {
+	return ctx.values[name].(T)
+}
@@ -0,0 +1,198 @@
+package stdflag
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// FlagConfig describes a flag of a command. It is created by MakeFlag, and defined on a flag.FlagSet
+// every time the command runs.
+type FlagConfig struct {
+	Name  string
+	Usage string
+	// Type is the Go type of the flag, as in `int` or `*time.Duration`.
+	Type string
+	// Required flags have no default value, and must be passed.
+	Required bool
+	// DefaultText is the formatted default value, and empty for required and optional flags.
+	DefaultText string
+	// IsBool is true for boolean flags, which are passed without a value.
+	IsBool   bool
+	newValue func() flagValue
+}
+
+// flagValue is a flag.Value holding a value of the type of its flag.
+type flagValue interface {
+	flag.Value
+	get() any
+}
+
+// value is the flagValue of flags of a basic type T.
+//
+// Optional flags, of type *T, hold nil until they are set.
+type value[T any] struct {
+	value      T
+	isSet      bool
+	isOptional bool
+	parse      func(string) (T, error)
+}
+
+func (v *value[T]) Set(s string) error {
+	parsed, err := v.parse(s)
+	if err != nil {
+		return err
+	}
+	v.value, v.isSet = parsed, true
+	return nil
+}
+
+func (v *value[T]) String() string {
+	if v == nil || (v.isOptional && !v.isSet) {
+		return ""
+	}
+	return fmt.Sprint(v.value)
+}
+
+// IsBoolFlag makes the flag package accept boolean flags without a value.
+func (v *value[T]) IsBoolFlag() bool {
+	_, isBool := any(v.value).(bool)
+	return isBool
+}
+
+func (v *value[T]) get() any {
+	if !v.isOptional {
+		return v.value
+	}
+	if !v.isSet {
+		return (*T)(nil)
+	}
+	result := v.value
+	return &result
+}
+
+// parsers holds the parse functions of the supported types.
+var parsers = map[reflect.Type]any{
+	reflect.TypeOf(""): func(s string) (string, error) { return s, nil },
+	reflect.TypeOf(false): func(s string) (bool, error) {
+		b, err := strconv.ParseBool(s)
+		return b, numError(err, "bool")
+	},
+	reflect.TypeOf(0): func(s string) (int, error) {
+		i, err := strconv.ParseInt(s, 0, strconv.IntSize)
+		return int(i), numError(err, "int")
+	},
+	reflect.TypeOf(int64(0)): func(s string) (int64, error) {
+		i, err := strconv.ParseInt(s, 0, 64)
+		return i, numError(err, "int64")
+	},
+	reflect.TypeOf(uint(0)): func(s string) (uint, error) {
+		i, err := strconv.ParseUint(s, 0, strconv.IntSize)
+		return uint(i), numError(err, "uint")
+	},
+	reflect.TypeOf(uint64(0)): func(s string) (uint64, error) {
+		i, err := strconv.ParseUint(s, 0, 64)
+		return i, numError(err, "uint64")
+	},
+	reflect.TypeOf(0.0): func(s string) (float64, error) {
+		f, err := strconv.ParseFloat(s, 64)
+		return f, numError(err, "float64")
+	},
+	reflect.TypeOf(time.Duration(0)): time.ParseDuration,
+}
+
+// numError replaces the errors of strconv, which repeat the parsed text, with the expected type.
+func numError(err error, typeName string) error {
+	if err == nil {
+		return nil
+	}
+	var numErr *strconv.NumError
+	if errors.As(err, &numErr) && numErr.Err == strconv.ErrRange {
+		return fmt.Errorf("value out of range for %s", typeName)
+	}
+	return fmt.Errorf("expected %s", typeName)
+}
+
+// IsSupported reports whether there are flags of a type, given its name as formatted by reflect.Type.String.
+//
+// goater uses this to reject the types the backend does not support.
+func IsSupported(typeName string) bool {
+	for supportedType := range parsers {
+		if supportedType.String() == typeName || "*"+supportedType.String() == typeName {
+			return true
+		}
+	}
+	return false
+}
+
+// MakeFlag creates a flag from a type and description values.
+//
+// T is one of the supported types, or a pointer to one of them for an optional flag.
+// Other flags are required if the default value is nil, except for boolean flags, which default to false.
+func MakeFlag[T any](name string, usage string, defaultValue any) FlagConfig {
+	return makeFlag(reflect.TypeOf(*new(T)), name, usage, defaultValue)
+}
+
+func makeFlag(t reflect.Type, name string, usage string, defaultValue any) FlagConfig {
+	isOptional := t.Kind() == reflect.Pointer
+	baseType := t
+	if isOptional {
+		baseType = t.Elem()
+	}
+	parse, exists := parsers[baseType]
+	if !exists {
+		panic("Unsupported flag type " + t.String())
+	}
+	f := FlagConfig{Name: name, Usage: usage, Type: t.String(), IsBool: baseType.Kind() == reflect.Bool}
+	f.Required = !isOptional && !f.IsBool && defaultValue == nil
+	if !isOptional && defaultValue != nil && !f.IsBool {
+		f.DefaultText = fmt.Sprint(defaultValue)
+	}
+	f.newValue = func() flagValue {
+		v := newValue(parse, isOptional)
+		if defaultValue != nil {
+			v.setDefault(defaultValue)
+		}
+		return v
+	}
+	return f
+}
+
+// newValue creates a value from one of the parse functions in parsers.
+func newValue(parse any, isOptional bool) defaultSetter {
+	switch parse := parse.(type) {
+	case func(string) (string, error):
+		return &value[string]{parse: parse, isOptional: isOptional}
+	case func(string) (bool, error):
+		return &value[bool]{parse: parse, isOptional: isOptional}
+	case func(string) (int, error):
+		return &value[int]{parse: parse, isOptional: isOptional}
+	case func(string) (int64, error):
+		return &value[int64]{parse: parse, isOptional: isOptional}
+	case func(string) (uint, error):
+		return &value[uint]{parse: parse, isOptional: isOptional}
+	case func(string) (uint64, error):
+		return &value[uint64]{parse: parse, isOptional: isOptional}
+	case func(string) (float64, error):
+		return &value[float64]{parse: parse, isOptional: isOptional}
+	case func(string) (time.Duration, error):
+		return &value[time.Duration]{parse: parse, isOptional: isOptional}
+	}
+	panic(fmt.Sprintf("Unsupported parse function %T", parse))
+}
+
+// defaultSetter is a flagValue that can be given a default value.
+type defaultSetter interface {
+	flagValue
+	setDefault(defaultValue any)
+}
+
+// setDefault sets the value to a default value, converting untyped constants such as `8080`
+// to the type of the value.
+func (v *value[T]) setDefault(defaultValue any) {
+	converted := reflect.ValueOf(defaultValue).Convert(reflect.TypeOf(v.value))
+	v.value = converted.Interface().(T)
+}
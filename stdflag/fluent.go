@@ -0,0 +1,50 @@
+package stdflag
+
+// The descriptors are the subset of goat's descriptors that the stdflag backend supports,
+// and are documented in the goat package. They are read by goater, and do nothing at runtime.
+
+type FluentFlag struct{}
+
+// Flag is goat.Flag, with the Name, Usage and Default descriptors.
+func Flag(any) FluentFlag {
+	return FluentFlag{}
+}
+
+func (f FluentFlag) Name(string) FluentFlag {
+	return FluentFlag{}
+}
+
+func (f FluentFlag) Usage(string) FluentFlag {
+	return FluentFlag{}
+}
+
+func (f FluentFlag) Default(any) FluentFlag {
+	return FluentFlag{}
+}
+
+type FluentSelf struct{}
+
+// Self is goat.Self.
+func Self() FluentSelf {
+	return FluentSelf{}
+}
+
+func (s FluentSelf) Name(string) FluentSelf {
+	return FluentSelf{}
+}
+
+func (s FluentSelf) Usage(string) FluentSelf {
+	return FluentSelf{}
+}
+
+func (s FluentSelf) Hidden() FluentSelf {
+	return FluentSelf{}
+}
+
+func (s FluentSelf) Deprecated(string) FluentSelf {
+	return FluentSelf{}
+}
+
+func (s FluentSelf) Category(string) FluentSelf {
+	return FluentSelf{}
+}
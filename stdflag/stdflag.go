@@ -0,0 +1,348 @@
+// Package stdflag is a goat runtime built on the standard library flag package, for tools that
+// should not depend on urfave/cli.
+//
+// Import it as goat, and generate the code using `goater -backend=stdflag`:
+//
+//	import goat "github.com/tmr232/goat/stdflag"
+//
+// It is a separate, minimal runtime: the goat package and its RunConfig stay built on urfave/cli,
+// and this package only covers what small tools need. It supports flags of basic types, optional flags,
+// defaults, required flags, help, subcommands, command groups and Context, and the Name, Usage and Default
+// descriptors of flags.
+//
+// goater reports positional arguments, flag groups (struct parameters), the other flag
+// descriptors (aliases, environment variables, validators and so on) and flag constraints
+// as errors. Config files, shell completion and the documentation commands have no
+// stdflag equivalent.
+package stdflag
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+)
+
+type RunConfig struct {
+	Flags  []FlagConfig
+	Action func(c *Context) error
+	Name   string
+	Usage  string
+	// Hidden commands are left out of the help.
+	Hidden bool
+	// Deprecated is a message shown when a deprecated command is used, and empty for other commands.
+	Deprecated string
+	// Category is the heading the command is listed under in the help.
+	Category string
+}
+
+var runConfigByFunction = make(map[reflect.Value]RunConfig)
+
+// Register registers the RunConfig of a function. It is only used in generated code.
+func Register(f any, config RunConfig) {
+	runConfigByFunction[reflect.ValueOf(f)] = config
+}
+
+// command is a command of an app, or the app itself.
+type command struct {
+	name        string
+	usage       string
+	function    reflect.Value
+	config      *RunConfig
+	subcommands []*command
+	hidden      bool
+	category    string
+}
+
+type AppPart interface {
+	appCommand() *command
+}
+
+type GoatCommand struct {
+	command *command
+}
+
+func (g *GoatCommand) appCommand() *command {
+	return g.command
+}
+
+type GoatGroup struct {
+	command *command
+}
+
+func (g *GoatGroup) appCommand() *command {
+	return g.command
+}
+
+func (g *GoatGroup) Usage(usage string) *GoatGroup {
+	g.command.usage = usage
+	return g
+}
+
+// Category sets the heading the group is listed under in the help.
+func (g *GoatGroup) Category(category string) *GoatGroup {
+	g.command.category = category
+	return g
+}
+
+func partsToCommands(parts []AppPart) []*command {
+	commands := make([]*command, len(parts))
+	for i, part := range parts {
+		commands[i] = part.appCommand()
+	}
+	return commands
+}
+
+func functionCommand(f any, subcommands []*command) *command {
+	function := reflect.ValueOf(f)
+	config, exists := runConfigByFunction[function]
+	if !exists {
+		panic("Function is not registered, run goater to generate the registration code")
+	}
+	return &command{
+		name:        config.Name,
+		usage:       config.Usage,
+		function:    function,
+		config:      &config,
+		subcommands: subcommands,
+		hidden:      config.Hidden,
+		category:    config.Category,
+	}
+}
+
+func Command(f any, subcommands ...AppPart) *GoatCommand {
+	return &GoatCommand{functionCommand(f, partsToCommands(subcommands))}
+}
+
+func Group(name string, subcommands ...AppPart) *GoatGroup {
+	return &GoatGroup{&command{name: name, subcommands: partsToCommands(subcommands)}}
+}
+
+type Application struct {
+	root *command
+	// Writer and ErrWriter default to os.Stdout and os.Stderr.
+	Writer    io.Writer
+	ErrWriter io.Writer
+}
+
+func App(name string, commands ...AppPart) Application {
+	return Application{
+		root:      &command{name: name, subcommands: partsToCommands(commands)},
+		Writer:    os.Stdout,
+		ErrWriter: os.Stderr,
+	}
+}
+
+// Usage sets the usage of the app, shown in the help.
+func (app Application) Usage(usage string) Application {
+	root := *app.root
+	root.usage = usage
+	app.root = &root
+	return app
+}
+
+// RunWithArgsE runs the app with the given arguments, where the first is the name of the app.
+func (app Application) RunWithArgsE(args []string) error {
+	ctx := &Context{app: app, flagsByAction: make(map[reflect.Value]map[string]any)}
+	return app.root.run(ctx, []string{app.root.name}, args[1:])
+}
+
+func (app Application) RunE() error {
+	return app.RunWithArgsE(os.Args)
+}
+
+func (app Application) Run() {
+	if err := app.RunE(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// RunE takes a free function and runs it as a CLI app.
+func RunE(f any) error {
+	root := functionCommand(f, nil)
+	root.name = os.Args[0]
+	return Application{root: root, Writer: os.Stdout, ErrWriter: os.Stderr}.RunE()
+}
+
+// Run takes a free function and runs it as a CLI app, terminating with a log if an error occurs.
+func Run(f any) {
+	if err := RunE(f); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run parses the flags of a command, and runs either its action or one of its subcommands.
+func (cmd *command) run(ctx *Context, path []string, args []string) error {
+	set := flag.NewFlagSet(strings.Join(path, " "), flag.ContinueOnError)
+	set.SetOutput(io.Discard)
+	values := make(map[string]flagValue)
+	if cmd.config != nil {
+		for _, f := range cmd.config.Flags {
+			values[f.Name] = f.newValue()
+			set.Var(values[f.Name], f.Name, f.Usage)
+		}
+	}
+
+	if err := set.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return cmd.printHelp(ctx.app.Writer, path)
+		}
+		fmt.Fprintf(ctx.app.Writer, "Incorrect Usage: %s\n\n", err)
+		if helpErr := cmd.printHelp(ctx.app.Writer, path); helpErr != nil {
+			return helpErr
+		}
+		return err
+	}
+
+	if cmd.config != nil {
+		passed := make(map[string]bool)
+		set.Visit(func(f *flag.Flag) {
+			passed[f.Name] = true
+		})
+		var missing []string
+		for _, f := range cmd.config.Flags {
+			if f.Required && !passed[f.Name] {
+				missing = append(missing, f.Name)
+			}
+		}
+		if len(missing) == 1 {
+			return fmt.Errorf("Required flag %q not set", missing[0])
+		}
+		if len(missing) > 1 {
+			return fmt.Errorf("Required flags %q not set", strings.Join(missing, ", "))
+		}
+
+		actionValues := make(map[string]any)
+		for name, value := range values {
+			actionValues[name] = value.get()
+		}
+		ctx.flagsByAction[cmd.function] = actionValues
+		ctx.values = actionValues
+	}
+
+	if set.NArg() > 0 && len(cmd.subcommands) > 0 {
+		name := set.Arg(0)
+		for _, subcommand := range cmd.subcommands {
+			if subcommand.name == name {
+				return subcommand.run(ctx, append(path[:len(path):len(path)], name), set.Args()[1:])
+			}
+		}
+		return fmt.Errorf("unknown command %q", name)
+	}
+	if set.NArg() > 0 {
+		return fmt.Errorf("expected no arguments, got %d", set.NArg())
+	}
+	if cmd.config == nil {
+		return cmd.printHelp(ctx.app.Writer, path)
+	}
+	if cmd.config.Deprecated != "" {
+		fmt.Fprintf(ctx.app.ErrWriter, "Warning: command %s is deprecated: %s\n", cmd.name, cmd.config.Deprecated)
+	}
+	return cmd.config.Action(ctx)
+}
+
+// printHelp prints the help of a command, in the same layout as the help of the urfave/cli backend.
+func (cmd *command) printHelp(w io.Writer, path []string) error {
+	var b strings.Builder
+	b.WriteString("NAME:\n   " + strings.Join(path, " "))
+	if cmd.usage != "" {
+		lines := strings.Split(cmd.usage, "\n")
+		for i := 1; i < len(lines); i++ {
+			if lines[i] != "" {
+				lines[i] = "   " + lines[i]
+			}
+		}
+		b.WriteString(" - " + strings.Join(lines, "\n"))
+	}
+	b.WriteString("\n\nUSAGE:\n   " + strings.Join(path, " ") + " [options]")
+	if len(cmd.visibleSubcommands()) > 0 {
+		b.WriteString(" command [command options]")
+	}
+	b.WriteString("\n")
+
+	if subcommands := cmd.visibleSubcommands(); len(subcommands) > 0 {
+		b.WriteString("\nCOMMANDS:\n")
+		width := 0
+		for _, subcommand := range subcommands {
+			width = max(width, len(subcommand.name))
+		}
+		var categories []string
+		for _, subcommand := range subcommands {
+			if subcommand.category == "" {
+				fmt.Fprintf(&b, "   %-*s  %s\n", width, subcommand.name, summary(subcommand.usage))
+			} else if !contains(categories, subcommand.category) {
+				categories = append(categories, subcommand.category)
+			}
+		}
+		for _, category := range categories {
+			b.WriteString("   " + category + ":\n")
+			for _, subcommand := range subcommands {
+				if subcommand.category == category {
+					fmt.Fprintf(&b, "     %-*s  %s\n", width, subcommand.name, summary(subcommand.usage))
+				}
+			}
+		}
+	}
+
+	b.WriteString("\nOPTIONS:\n")
+	var names, usages []string
+	if cmd.config != nil {
+		for _, f := range cmd.config.Flags {
+			name := "--" + f.Name
+			if !f.IsBool {
+				name += " value"
+			}
+			usage := f.Usage
+			if f.DefaultText != "" {
+				usage = strings.TrimSpace(usage + " (default: " + f.DefaultText + ")")
+			}
+			names, usages = append(names, name), append(usages, usage)
+		}
+	}
+	names, usages = append(names, "--help, -h"), append(usages, "show help")
+	width := 0
+	for _, name := range names {
+		width = max(width, len(name))
+	}
+	for i, name := range names {
+		fmt.Fprintf(&b, "   %s\n", strings.TrimRight(fmt.Sprintf("%-*s  %s", width, name, usages[i]), " "))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (cmd *command) visibleSubcommands() []*command {
+	var visible []*command
+	for _, subcommand := range cmd.subcommands {
+		if !subcommand.hidden {
+			visible = append(visible, subcommand)
+		}
+	}
+	return visible
+}
+
+// summary is the first paragraph of a usage, in a single line.
+func summary(usage string) string {
+	paragraph, _, _ := strings.Cut(strings.TrimSpace(usage), "\n\n")
+	return strings.Join(strings.Fields(paragraph), " ")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
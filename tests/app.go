@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"fmt"
 	"github.com/tmr232/goat"
 	"io"
 )
@@ -15,8 +16,42 @@ func FlagsWithUsage(a, b, c int) {
 	goat.Flag(c).Usage("C.")
 }
 
+// Copy copies a file.
+func Copy(src, dst string) {
+	goat.Self().Category("Files")
+	goat.Arg(src).Name("SRC")
+	goat.Arg(dst).Name("DST")
+}
+
+// Database works with a database.
+func Database(db DBOptions) {
+	goat.Self().Category("Admin")
+}
+
+// Migrate migrates the database.
+func Migrate(ctx *goat.Context) error {
+	db, err := goat.GetFlag[DBOptions](ctx, Database, "db")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(ctx.GetWriter(), db.Host, db.Port)
+	return nil
+}
+
+// Legacy does things the old way.
+func Legacy(ctx *goat.Context) {
+	goat.Self().Deprecated("use NoFlags")
+
+	fmt.Fprintln(ctx.GetWriter(), "legacy")
+}
+
+// Internal is only used for debugging.
+func Internal() {
+	goat.Self().Hidden()
+}
+
 func getApp(stdout, stderr io.Writer) goat.Application {
-	app := goat.App("test-app", goat.Command(NoFlags), goat.Command(FlagsWithUsage))
+	app := goat.App("test-app", goat.Command(NoFlags), goat.Command(FlagsWithUsage), goat.Command(Copy), goat.Command(Database, goat.Command(Migrate)), goat.Command(Legacy), goat.Command(Internal))
 	app.Writer = stdout
 	app.ErrWriter = stderr
 
@@ -24,7 +59,14 @@ func getApp(stdout, stderr io.Writer) goat.Application {
 }
 
 var appCmds = []string{
+	"--help",
 	"NoFlags --help",
 	"NoFlags --a-flag",
 	"FlagsWithUsage --help",
+	"Copy --help",
+	"Copy a",
+	"Database --help",
+	"Database --db-host db.local Migrate",
+	"Legacy",
+	"Internal",
 }
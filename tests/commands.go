@@ -2,7 +2,15 @@ package tests
 
 import (
 	"fmt"
+	"github.com/pkg/errors"
 	"github.com/tmr232/goat"
+	"github.com/tmr232/goat/flags"
+	"github.com/urfave/cli/v2"
+	"math/big"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
 )
 
 //go:generate go run github.com/tmr232/goat/cmd/goater
@@ -37,6 +45,388 @@ func optionalFlag(num *int, ctx *goat.Context) {
 	}
 }
 
+func floatFlags(ratio float64, scale *float32, ctx *goat.Context) {
+	goat.Flag(ratio).Usage("A ratio.")
+	goat.Flag(scale).Usage("An optional scale.")
+
+	if scale == nil {
+		fmt.Fprintln(ctx.GetWriter(), ratio)
+	} else {
+		fmt.Fprintln(ctx.GetWriter(), ratio, *scale)
+	}
+}
+
+func defaultFloat(ratio float64, scale float32) {
+	goat.Flag(ratio).Default(1)
+	goat.Flag(scale).Default(0.5)
+}
+
+func sizedInts(port uint16, offset int8, size uint64, id *int64, ctx *goat.Context) {
+	goat.Flag(port).Usage("A port number.")
+	goat.Flag(offset).Default(-1)
+	goat.Flag(size).Default(1 << 40)
+
+	if id == nil {
+		fmt.Fprintln(ctx.GetWriter(), port, offset, size)
+	} else {
+		fmt.Fprintln(ctx.GetWriter(), port, offset, size, *id)
+	}
+}
+
+func timeFlags(timeout time.Duration, since *time.Time, until time.Time, ctx *goat.Context) {
+	goat.Flag(timeout).Default(5 * time.Second)
+	goat.Flag(until).
+		Layout("2006-01-02").
+		Usage("The last day.")
+
+	if since != nil {
+		fmt.Fprintln(ctx.GetWriter(), timeout, since.Format(time.RFC3339), until.Format(time.RFC3339))
+	} else {
+		fmt.Fprintln(ctx.GetWriter(), timeout, until.Format(time.RFC3339))
+	}
+}
+
+func sliceFlags(tags []string, ids []int, weights []float64, ctx *goat.Context) {
+	goat.Flag(tags).
+		Name("tag").
+		Default([]string{"x"})
+	goat.Flag(ids).
+		Name("id").
+		Separator(",")
+	goat.Flag(weights).Name("weight")
+
+	fmt.Fprintln(ctx.GetWriter(), len(tags), tags, len(ids), ids, len(weights), weights)
+}
+
+func mapFlags(labels map[string]string, limits map[string]int, ctx *goat.Context) {
+	goat.Flag(labels).
+		Name("label").
+		Usage("Labels to apply.")
+	goat.Flag(limits).
+		Name("limit").
+		Separator(",").
+		Default(map[string]int{"cpu": 1})
+
+	fmt.Fprintln(ctx.GetWriter(), labels, limits)
+}
+
+type Format string
+
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+)
+
+type Color int
+
+const (
+	Red Color = iota
+	Green
+	Blue
+)
+
+func enumFlags(format Format, color *Color, ctx *goat.Context) {
+	goat.Flag(format).Usage("Output format.")
+	goat.Flag(color).Usage("An optional color.")
+
+	if color == nil {
+		fmt.Fprintln(ctx.GetWriter(), format)
+	} else {
+		fmt.Fprintln(ctx.GetWriter(), format, *color)
+	}
+}
+
+func defaultEnum(format Format) {
+	goat.Flag(format).Default(YAML)
+}
+
+// Level implements flag.Value.
+type Level struct {
+	name string
+}
+
+func (l *Level) Set(s string) error {
+	if s != strings.ToLower(s) {
+		return errors.New("must be lower-case")
+	}
+	l.name = s
+	return nil
+}
+
+func (l *Level) String() string {
+	return l.name
+}
+
+func textFlags(ip net.IP, addr *netip.Addr, count *big.Int, level Level, ctx *goat.Context) {
+	goat.Flag(ip).Usage("An IP address.")
+	goat.Flag(level).Default(Level{"info"})
+
+	fmt.Fprintln(ctx.GetWriter(), ip, addr, count, level.name)
+}
+
+func textDefaults(since time.Time, addr netip.Addr, level Level, ctx *goat.Context) {
+	goat.Flag(since).
+		Layout("2006-01-02").
+		Default("2024-01-01")
+	goat.Flag(addr).Default("127.0.0.1")
+	goat.Flag(level).Default("warn")
+
+	fmt.Fprintln(ctx.GetWriter(), since.Format(time.RFC3339), addr, level.name)
+}
+
+// Celsius has a type handler of its own.
+type Celsius float64
+
+type celsiusHandler struct{}
+
+func (celsiusHandler) MakeFlag(name, usage string, defaultValue any) cli.Flag {
+	if defaultValue == nil {
+		return &cli.Float64Flag{Name: name, Usage: usage, Required: true}
+	}
+	return &cli.Float64Flag{Name: name, Usage: usage, Value: float64(defaultValue.(Celsius))}
+}
+
+func (celsiusHandler) GetFlag(c *cli.Context, name string) any {
+	return Celsius(c.Float64(name))
+}
+
+func init() {
+	flags.RegisterTypeHandler[Celsius](celsiusHandler{})
+}
+
+func registeredType(temperature Celsius, ctx *goat.Context) {
+	goat.Flag(temperature).
+		Usage("The temperature, in Celsius.").
+		Default(Celsius(20))
+
+	fmt.Fprintln(ctx.GetWriter(), temperature)
+}
+
+func copyFile(src, dst string, force bool, ctx *goat.Context) {
+	goat.Arg(src).
+		Name("SRC").
+		Usage("The file to copy.")
+	goat.Arg(dst).
+		Name("DST").
+		Usage("Where to copy it.")
+	goat.Flag(force).Usage("Overwrite an existing file.")
+
+	fmt.Fprintln(ctx.GetWriter(), src, dst, force)
+}
+
+func optionalArgs(count int, unit string, format *Format, ctx *goat.Context) {
+	goat.Arg(count)
+	goat.Arg(unit).Default("bytes")
+	goat.Arg(format)
+
+	if format == nil {
+		fmt.Fprintln(ctx.GetWriter(), count, unit)
+	} else {
+		fmt.Fprintln(ctx.GetWriter(), count, unit, *format)
+	}
+}
+
+func variadicArgs(ctx *goat.Context, verbose bool, files ...string) {
+	fmt.Fprintln(ctx.GetWriter(), verbose, len(files), files)
+}
+
+func variadicInts(ctx *goat.Context, base int, numbers ...int) {
+	goat.Arg(base).Usage("The number to start from.")
+	goat.Arg(numbers).
+		Name("N").
+		Usage("Numbers to add.")
+
+	sum := base
+	for _, number := range numbers {
+		sum += number
+	}
+	fmt.Fprintln(ctx.GetWriter(), sum)
+}
+
+type DBOptions struct {
+	Host    string        `goat:"usage=The database host.,default=localhost"`
+	Port    int           `goat:"default=5432"`
+	User    *string       `goat:"usage=An optional user."`
+	Timeout time.Duration `goat:"name=connect-timeout,default=5s"`
+	Secret  string        `goat:"-"`
+}
+
+type HTTPOptions struct {
+	Port      uint16 `goat:"usage=Port to listen on, on all interfaces.,default=8080"`
+	TLS       bool
+	LogFormat Format `goat:"default=json"`
+}
+
+func deploy(db DBOptions, http HTTPOptions, dryRun bool, ctx *goat.Context) {
+	goat.Flag(dryRun).Usage("Only print what would be deployed.")
+
+	user := "<none>"
+	if db.User != nil {
+		user = *db.User
+	}
+	fmt.Fprintln(ctx.GetWriter(), db.Host, db.Port, user, db.Timeout, http.Port, http.TLS, http.LogFormat, dryRun)
+}
+
+func countFlags(ctx *goat.Context, verbose int, level int) {
+	goat.Flag(verbose).
+		Usage("Increase verbosity.").
+		Count()
+	goat.Flag(level).
+		Count().
+		Default(2)
+
+	fmt.Fprintln(ctx.GetWriter(), verbose, level)
+}
+
+func aliasedFlags(ctx *goat.Context, count int, verbose bool) {
+	goat.Flag(count).
+		Short('n').
+		Alias("number").
+		Default(1)
+	goat.Flag(verbose).
+		Short('v')
+
+	fmt.Fprintln(ctx.GetWriter(), count, verbose)
+}
+
+func envFlags(ctx *goat.Context, token string, region string, retries *int) {
+	goat.Flag(token).
+		Usage("The API token.").
+		EnvVar("APP_TOKEN", "TOKEN")
+	goat.Flag(region).
+		Default("us").
+		EnvVar("APP_REGION")
+
+	retriesText := "none"
+	if retries != nil {
+		retriesText = fmt.Sprint(*retries)
+	}
+	fmt.Fprintln(ctx.GetWriter(), token, region, retriesText)
+}
+
+func envPrefix(ctx *goat.Context, name string, db DBOptions) {
+	goat.Flag(name).
+		EnvVar("NAME")
+
+	fmt.Fprintln(ctx.GetWriter(), name, db.Host, db.Port)
+}
+
+func deprecatedFlags(ctx *goat.Context, name string, oldName *string, debug bool) {
+	goat.Flag(name).Default("goat")
+	goat.Flag(oldName).
+		Name("old-name").
+		Usage("The name to use.").
+		Deprecated("use --name")
+	goat.Flag(debug).Hidden()
+
+	if oldName != nil {
+		name = *oldName
+	}
+	fmt.Fprintln(ctx.GetWriter(), name, debug)
+}
+
+func flagConstraints(ctx *goat.Context, json bool, yaml bool, user *string, password *string) {
+	goat.Exclusive(json, yaml)
+	goat.Together(user, password)
+
+	name := "anonymous"
+	if user != nil {
+		name = *user
+	}
+	fmt.Fprintln(ctx.GetWriter(), json, yaml, name)
+}
+
+func checkNotRoot(user *string) error {
+	if user != nil && *user == "root" {
+		return errors.New("root is not allowed")
+	}
+	return nil
+}
+
+func validatedFlags(ctx *goat.Context, port int, name string, level string, user *string, tags []string) {
+	goat.Flag(port).
+		Usage("Port to listen on.").
+		Range(1, 65535).
+		Default(8080)
+	goat.Flag(name).
+		MinLen(3).
+		Pattern("^[a-z]+$")
+	goat.Flag(level).
+		OneOf("debug", "info").
+		Default("info")
+	goat.Flag(user).
+		Validate(checkNotRoot)
+	goat.Flag(tags).
+		MinLen(1)
+
+	fmt.Fprintln(ctx.GetWriter(), port, name, level, tags)
+}
+
+func categorizedFlags(ctx *goat.Context, host string, port int, verbose bool, retries int) {
+	goat.Flag(host).
+		Default("localhost").
+		Category("Networking")
+	goat.Flag(port).
+		Default(80).
+		Category("Networking")
+	goat.Flag(retries).
+		Default(3).
+		Category("Behaviour")
+
+	fmt.Fprintln(ctx.GetWriter(), host, port, verbose, retries)
+}
+
+func fileFlags(ctx *goat.Context, input string, output *string, format Format) {
+	goat.Flag(input).
+		Usage("The file to read.").
+		File()
+	goat.Flag(output).
+		Short('o').
+		File()
+
+	fmt.Fprintln(ctx.GetWriter(), input, format)
+}
+
+func clusterNames(ctx *goat.Context, prefix string) []string {
+	return []string{"prod-eu", "prod-us", "staging"}
+}
+
+func dynamicFlags(ctx *goat.Context, cluster string, replicas int) {
+	goat.Flag(cluster).
+		Short('c').
+		Complete(clusterNames)
+
+	fmt.Fprintln(ctx.GetWriter(), cluster, replicas)
+}
+
+func serveV1(ctx *goat.Context, port int, host string, verbose bool, format *Format, color *Color, workers int, src string, extra ...string) {
+	goat.Self().Name("serve")
+	goat.Flag(port).Default(8080).EnvVar("PORT")
+	goat.Flag(host).Default("localhost")
+	goat.Flag(verbose).Short('v')
+	goat.Flag(workers).Default(4)
+	goat.Arg(src)
+	goat.Arg(extra)
+}
+
+func serveV2(ctx *goat.Context, port string, address string, verbose bool, format Format, color *Color, workers int, debug bool, src string, dst string) {
+	goat.Self().Name("serve")
+	goat.Flag(port).Default("8080")
+	goat.Flag(address).Alias("host").Default("localhost")
+	goat.Flag(workers).Default(8)
+	goat.Flag(debug).Deprecated("use --verbose")
+	goat.Arg(src)
+	goat.Arg(dst)
+}
+
+func statusV1(ctx *goat.Context) {
+	goat.Self().Name("status")
+}
+
+func statusV2(ctx *goat.Context, token string) {
+	goat.Self().Name("status")
+}
+
 func Register() {
 	goat.Command(noFlags)
 	goat.Command(intFlag)
@@ -45,4 +435,34 @@ func Register() {
 	goat.Command(flagUsage)
 	goat.Command(defaultValue)
 	goat.Command(optionalFlag)
+	goat.Command(floatFlags)
+	goat.Command(defaultFloat)
+	goat.Command(sizedInts)
+	goat.Command(timeFlags)
+	goat.Command(sliceFlags)
+	goat.Command(mapFlags)
+	goat.Command(enumFlags)
+	goat.Command(defaultEnum)
+	goat.Command(textFlags)
+	goat.Command(textDefaults)
+	goat.Command(registeredType)
+	goat.Command(copyFile)
+	goat.Command(optionalArgs)
+	goat.Command(variadicArgs)
+	goat.Command(variadicInts)
+	goat.Command(deploy)
+	goat.Command(countFlags)
+	goat.Command(aliasedFlags)
+	goat.Command(envFlags)
+	goat.Command(envPrefix)
+	goat.Command(deprecatedFlags)
+	goat.Command(flagConstraints)
+	goat.Command(validatedFlags)
+	goat.Command(categorizedFlags)
+	goat.Command(fileFlags)
+	goat.Command(dynamicFlags)
+	goat.Command(serveV1)
+	goat.Command(serveV2)
+	goat.Command(statusV1)
+	goat.Command(statusV2)
 }
@@ -2,8 +2,11 @@ package tests
 
 import (
 	"bytes"
+	"fmt"
 	"github.com/approvals/go-approval-tests"
 	"github.com/tmr232/goat"
+	"github.com/urfave/cli/v2"
+	"os"
 	"strings"
 	"testing"
 )
@@ -28,6 +31,50 @@ func Test_things(t *testing.T) {
 		{"intFlag without flag", args{intFlag, Args()}, false},
 		{"renamedFlag with correct name", args{renamedFlag, Args("--flag", "1")}, true},
 		{"renamedFlag with wrong (original) name", args{renamedFlag, Args("--bla", "1")}, false},
+		{"floatFlags with valid flags", args{floatFlags, Args("--ratio", "0.25", "--scale", "2")}, true},
+		{"floatFlags without optional flag", args{floatFlags, Args("--ratio", "0.25")}, true},
+		{"floatFlags without required flag", args{floatFlags, Args("--scale", "2")}, false},
+		{"floatFlags with invalid flag", args{floatFlags, Args("--ratio", "a")}, false},
+		{"floatFlags with out of range float32", args{floatFlags, Args("--ratio", "1", "--scale", "1e40")}, false},
+		{"defaultFloat", args{defaultFloat, Args()}, true},
+		{"sizedInts with valid flags", args{sizedInts, Args("--port", "8080", "--id", "-5")}, true},
+		{"sizedInts with out of range port", args{sizedInts, Args("--port", "70000")}, false},
+		{"sizedInts with negative unsigned", args{sizedInts, Args("--port", "-1")}, false},
+		{"sizedInts without required flag", args{sizedInts, Args()}, false},
+		{"timeFlags with valid flags", args{timeFlags, Args("--timeout", "1m", "--until", "2022-10-01")}, true},
+		{"timeFlags with invalid duration", args{timeFlags, Args("--timeout", "1", "--until", "2022-10-01")}, false},
+		{"timeFlags with wrong layout", args{timeFlags, Args("--until", "2022-10-01T00:00:00Z")}, false},
+		{"sliceFlags without flags", args{sliceFlags, Args()}, true},
+		{"sliceFlags with invalid item", args{sliceFlags, Args("--id", "1,a")}, false},
+		{"mapFlags without flags", args{mapFlags, Args()}, true},
+		{"mapFlags with missing value", args{mapFlags, Args("--label", "env")}, false},
+		{"mapFlags with missing key", args{mapFlags, Args("--label", "=prod")}, false},
+		{"enumFlags with valid flags", args{enumFlags, Args("--format", "json", "--color", "Blue")}, true},
+		{"enumFlags without required flag", args{enumFlags, Args("--color", "Blue")}, false},
+		{"enumFlags with invalid value", args{enumFlags, Args("--format", "xml")}, false},
+		{"enumFlags with integer enum value", args{enumFlags, Args("--format", "json", "--color", "2")}, false},
+		{"defaultEnum", args{defaultEnum, Args()}, true},
+		{"textFlags with valid flags", args{textFlags, Args("--ip", "10.0.0.1", "--addr", "::1", "--count", "12345678901234567890")}, true},
+		{"textFlags without required flag", args{textFlags, Args("--addr", "::1")}, false},
+		{"textFlags with invalid address", args{textFlags, Args("--ip", "10.0.0.1", "--addr", "nope")}, false},
+		{"textFlags with invalid flag.Value", args{textFlags, Args("--ip", "10.0.0.1", "--level", "DEBUG")}, false},
+		{"copyFile with arguments", args{copyFile, Args("--force", "a", "b")}, true},
+		{"copyFile with too few arguments", args{copyFile, Args("a")}, false},
+		{"copyFile with too many arguments", args{copyFile, Args("a", "b", "c")}, false},
+		{"optionalArgs with required argument", args{optionalArgs, Args("1")}, true},
+		{"optionalArgs with all arguments", args{optionalArgs, Args("1", "KB", "json")}, true},
+		{"optionalArgs without arguments", args{optionalArgs, Args()}, false},
+		{"optionalArgs with invalid argument", args{optionalArgs, Args("a")}, false},
+		{"optionalArgs with invalid enum argument", args{optionalArgs, Args("1", "KB", "xml")}, false},
+		{"variadicArgs without arguments", args{variadicArgs, Args()}, true},
+		{"variadicArgs with arguments", args{variadicArgs, Args("--verbose", "a", "b", "--", "-c")}, true},
+		{"variadicInts with arguments", args{variadicInts, Args("1", "2", "3")}, true},
+		{"variadicInts without required argument", args{variadicInts, Args()}, false},
+		{"variadicInts with invalid element", args{variadicInts, Args("1", "2", "a")}, false},
+		{"deploy with defaults", args{deploy, Args()}, true},
+		{"deploy with group flags", args{deploy, Args("--db-host", "db.local", "--db-connect-timeout", "1m", "--http-tls")}, true},
+		{"deploy with invalid group flag", args{deploy, Args("--db-port", "a")}, false},
+		{"deploy with field name", args{deploy, Args("--host", "db.local")}, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -63,18 +110,374 @@ func Test_apps(t *testing.T) {
 		{"optionalFlag --help", args{optionalFlag, Args("--help")}},
 		{"optionalFlag", args{optionalFlag, Args()}},
 		{"optionalFlag --num 10", args{optionalFlag, Args("--num", "10")}},
+		{"floatFlags --help", args{floatFlags, Args("--help")}},
+		{"floatFlags --ratio 0.25", args{floatFlags, Args("--ratio", "0.25")}},
+		{"floatFlags --ratio 0.25 --scale 1.5", args{floatFlags, Args("--ratio", "0.25", "--scale", "1.5")}},
+		{"floatFlags --ratio a", args{floatFlags, Args("--ratio", "a")}},
+		{"defaultFloat --help", args{defaultFloat, Args("--help")}},
+		{"sizedInts --help", args{sizedInts, Args("--help")}},
+		{"sizedInts --port 8080 --id -5", args{sizedInts, Args("--port", "8080", "--id", "-5")}},
+		{"sizedInts --port 70000", args{sizedInts, Args("--port", "70000")}},
+		{"timeFlags --help", args{timeFlags, Args("--help")}},
+		{"timeFlags --until 2022-10-01", args{timeFlags, Args("--until", "2022-10-01")}},
+		{"timeFlags --timeout 1h30m --since 2022-09-01T12:00:00+02:00 --until 2022-10-01", args{timeFlags, Args("--timeout", "1h30m", "--since", "2022-09-01T12:00:00+02:00", "--until", "2022-10-01")}},
+		{"timeFlags --until 1", args{timeFlags, Args("--until", "1")}},
+		{"sliceFlags --help", args{sliceFlags, Args("--help")}},
+		{"sliceFlags", args{sliceFlags, Args()}},
+		{"sliceFlags --tag a --tag b,c --id 1,2 --id 3 --weight 0.5", args{sliceFlags, Args("--tag", "a", "--tag", "b,c", "--id", "1,2", "--id", "3", "--weight", "0.5")}},
+		{"sliceFlags --id 1,a", args{sliceFlags, Args("--id", "1,a")}},
+		{"mapFlags --help", args{mapFlags, Args("--help")}},
+		{"mapFlags", args{mapFlags, Args()}},
+		{"mapFlags --label env=prod --label team=core --label env=dev --limit mem=2,disk=3", args{mapFlags, Args("--label", "env=prod", "--label", "team=core", "--label", "env=dev", "--limit", "mem=2,disk=3")}},
+		{"mapFlags --label env", args{mapFlags, Args("--label", "env")}},
+		{"mapFlags --limit mem=a", args{mapFlags, Args("--limit", "mem=a")}},
+		{"enumFlags --help", args{enumFlags, Args("--help")}},
+		{"enumFlags --format yaml", args{enumFlags, Args("--format", "yaml")}},
+		{"enumFlags --format json --color Green", args{enumFlags, Args("--format", "json", "--color", "Green")}},
+		{"enumFlags --format xml", args{enumFlags, Args("--format", "xml")}},
+		{"defaultEnum --help", args{defaultEnum, Args("--help")}},
+		{"textFlags --help", args{textFlags, Args("--help")}},
+		{"textFlags --ip 10.0.0.1", args{textFlags, Args("--ip", "10.0.0.1")}},
+		{"textFlags --ip 10.0.0.1 --addr ::1 --count 12345678901234567890 --level debug", args{textFlags, Args("--ip", "10.0.0.1", "--addr", "::1", "--count", "12345678901234567890", "--level", "debug")}},
+		{"textFlags --ip 10.0.0.1 --level DEBUG", args{textFlags, Args("--ip", "10.0.0.1", "--level", "DEBUG")}},
+		{"textDefaults --help", args{textDefaults, Args("--help")}},
+		{"textDefaults", args{textDefaults, Args()}},
+		{"textDefaults --since 2024-02-01 --level debug", args{textDefaults, Args("--since", "2024-02-01", "--level", "debug")}},
+		{"registeredType --help", args{registeredType, Args("--help")}},
+		{"registeredType --temperature 36.6", args{registeredType, Args("--temperature", "36.6")}},
+		{"copyFile --help", args{copyFile, Args("--help")}},
+		{"copyFile --force a b", args{copyFile, Args("--force", "a", "b")}},
+		{"copyFile a", args{copyFile, Args("a")}},
+		{"copyFile a b c", args{copyFile, Args("a", "b", "c")}},
+		{"optionalArgs --help", args{optionalArgs, Args("--help")}},
+		{"optionalArgs 1", args{optionalArgs, Args("1")}},
+		{"optionalArgs 1 KB json", args{optionalArgs, Args("1", "KB", "json")}},
+		{"optionalArgs a", args{optionalArgs, Args("a")}},
+		{"variadicArgs --help", args{variadicArgs, Args("--help")}},
+		{"variadicArgs", args{variadicArgs, Args()}},
+		{"variadicArgs --verbose -- a -b --c", args{variadicArgs, Args("--verbose", "--", "a", "-b", "--c")}},
+		{"variadicArgs -- --verbose", args{variadicArgs, Args("--", "--verbose")}},
+		{"variadicInts --help", args{variadicInts, Args("--help")}},
+		{"variadicInts 1 2 3", args{variadicInts, Args("1", "2", "3")}},
+		{"variadicInts 1 2 a", args{variadicInts, Args("1", "2", "a")}},
+		{"deploy --help", args{deploy, Args("--help")}},
+		{"deploy", args{deploy, Args()}},
+		{"countFlags --help", args{countFlags, Args("--help")}},
+		{"countFlags", args{countFlags, Args()}},
+		{"countFlags -vvv -l", args{countFlags, Args("-vvv", "-l")}},
+		{"countFlags --verbose --verbose --level", args{countFlags, Args("--verbose", "--verbose", "--level")}},
+		{"aliasedFlags --help", args{aliasedFlags, Args("--help")}},
+		{"aliasedFlags -n 3 -v", args{aliasedFlags, Args("-n", "3", "-v")}},
+		{"aliasedFlags --number 3", args{aliasedFlags, Args("--number", "3")}},
+		{"aliasedFlags --count 3", args{aliasedFlags, Args("--count", "3")}},
+		{"deprecatedFlags --help", args{deprecatedFlags, Args("--help")}},
+		{"deprecatedFlags --old-name kid --debug", args{deprecatedFlags, Args("--old-name", "kid", "--debug")}},
+		{"flagConstraints --json --yaml", args{flagConstraints, Args("--json", "--yaml")}},
+		{"flagConstraints --user goat", args{flagConstraints, Args("--user", "goat")}},
+		{"flagConstraints --yaml --user goat --password secret", args{flagConstraints, Args("--yaml", "--user", "goat", "--password", "secret")}},
+		{"validatedFlags --help", args{validatedFlags, Args("--help")}},
+		{"validatedFlags --name goat --tags a", args{validatedFlags, Args("--name", "goat", "--tags", "a")}},
+		{"validatedFlags --port 0 --name Go --level trace --user root", args{validatedFlags, Args("--port", "0", "--name", "Go", "--level", "trace", "--user", "root")}},
+		{"categorizedFlags --help", args{categorizedFlags, Args("--help")}},
+		{"deploy --db-host db.local --db-user admin --db-connect-timeout 1m --http-port 80 --http-tls --http-log-format yaml --dryRun", args{deploy, Args("--db-host", "db.local", "--db-user", "admin", "--db-connect-timeout", "1m", "--http-port", "80", "--http-tls", "--http-log-format", "yaml", "--dryRun")}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			app := goat.FuncToApp(tt.args.f)
 			stdout := &bytes.Buffer{}
 			app.Writer = stdout
+			app.ErrWriter = stdout
 			_ = app.Run(tt.args.args)
 			approvals.Verify(t, stdout)
 		})
 	}
 }
 
+// runApproved runs the app with the arguments, and the environment variables set,
+// verifying its output along with the error it returns.
+func runApproved(t *testing.T, app goat.Application, args string, env map[string]string) {
+	t.Helper()
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+	argv := append([]string{app.Name}, strings.Split(args, " ")...)
+	stdout := &bytes.Buffer{}
+	stdout.WriteString(strings.Join(argv, " ") + "\n")
+	stdout.WriteString("-----------------------------------------------------\n\n")
+	app.Writer = stdout
+	app.ErrWriter = stdout
+	if err := app.RunWithArgsE(argv); err != nil {
+		stdout.WriteString("\n" + err.Error() + "\n")
+	}
+	approvals.Verify(t, stdout)
+}
+
+func Test_env(t *testing.T) {
+	app := goat.App("env-app", goat.Command(envFlags), goat.Command(envPrefix)).EnvPrefix("APP")
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		args string
+	}{
+		{"envFlags help", nil, "envFlags --help"},
+		{"envFlags without env", nil, "envFlags"},
+		{"envFlags with env", map[string]string{"TOKEN": "secret", "APP_REGION": "eu", "APP_RETRIES": "3"}, "envFlags"},
+		{"envFlags first env var wins", map[string]string{"APP_TOKEN": "first", "TOKEN": "second", "APP_REGION": "eu", "APP_RETRIES": "1"}, "envFlags"},
+		{"envFlags flags override env", map[string]string{"TOKEN": "secret", "APP_REGION": "eu", "APP_RETRIES": "3"}, "envFlags --token flag --region il --retries 5"},
+		{"envPrefix help", nil, "envPrefix --help"},
+		{"envPrefix with env", map[string]string{"NAME": "goat", "APP_DB_HOST": "db.local", "APP_DB_PORT": "1234"}, "envPrefix"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runApproved(t, app, tt.args, tt.env)
+		})
+	}
+}
+
+func Test_config(t *testing.T) {
+	app := goat.App("config-app",
+		goat.Command(deploy),
+		goat.Command(sliceFlags),
+		goat.Command(mapFlags),
+		goat.Command(countFlags),
+		goat.Command(timeFlags),
+		goat.Group("remote", goat.Command(envFlags)),
+	).ConfigFile("testdata/config.yaml")
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		args string
+	}{
+		{"help", nil, "--help"},
+		{"default file", nil, "deploy"},
+		{"flags override config", nil, "deploy --db-port 1 --http-log-format json"},
+		{"slices", nil, "sliceFlags"},
+		{"slices are replaced", nil, "sliceFlags --tag c"},
+		{"counts are replaced", nil, "countFlags -v"},
+		{"nested command", nil, "remote envFlags"},
+		{"env overrides config", map[string]string{"APP_TOKEN": "from-env", "APP_REGION": "il"}, "remote envFlags"},
+		{"json", nil, "--config testdata/config.json deploy"},
+		{"json map", nil, "--config=testdata/config.json mapFlags"},
+		{"toml", nil, "--config testdata/config.toml deploy"},
+		{"toml date", nil, "--config testdata/config.toml timeFlags"},
+		{"missing required flag", nil, "--config testdata/config.toml remote envFlags"},
+		{"missing file", nil, "--config testdata/missing.yaml deploy"},
+		{"invalid value", nil, "--config testdata/invalid.yaml deploy"},
+		{"invalid file", nil, "--config testdata/broken.json deploy"},
+		{"config as a flag value", nil, "deploy --db-user --config=testdata/missing.yaml"},
+		{"value instead of table", nil, "--config testdata/nested.yaml remote envFlags"},
+		{"print config", nil, "--print-config deploy --db-user admin"},
+		{"print config json", nil, "--config testdata/config.json --print-config mapFlags --limit disk=2"},
+		{"print config toml", nil, "--config testdata/config.toml --print-config remote envFlags --token flag"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runApproved(t, app, tt.args, tt.env)
+		})
+	}
+}
+
+func Test_configAppFlags(t *testing.T) {
+	app := goat.App("config-app", goat.Command(deploy))
+	app.Flags = []cli.Flag{
+		&cli.StringFlag{Name: "region", Required: true},
+		&cli.StringFlag{Name: "zone"},
+	}
+	app = app.ConfigFile("testdata/app.yaml")
+
+	tests := []string{
+		"--print-config deploy",
+		"--config testdata/app.json --print-config deploy",
+		"--zone flag --config testdata/app.json --print-config deploy",
+		"--config testdata/app.json --region flag --print-config deploy",
+	}
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			runApproved(t, app, tt, nil)
+		})
+	}
+}
+
+// The config file is read when the app is run using cli directly.
+func Test_configCliRun(t *testing.T) {
+	app := goat.App("config-app", goat.Command(deploy)).ConfigFile("testdata/config.yaml")
+	stdout := &bytes.Buffer{}
+	app.Writer = stdout
+	if err := app.App.Run([]string{"config-app", "--config", "testdata/config.json", "deploy"}); err != nil {
+		t.Fatal(err)
+	}
+	approvals.Verify(t, stdout)
+}
+
+func Test_completion(t *testing.T) {
+	app := goat.App("comp-app",
+		goat.Command(fileFlags),
+		goat.Command(aliasedFlags),
+		goat.Group("remote", goat.Command(deprecatedFlags), goat.Command(copyFile), goat.Command(dynamicFlags)).Usage("Remote commands."),
+	).ConfigFile("~/.comp-app.yaml").CompletionCommand()
+
+	tests := []string{
+		"--help",
+		"completion bash",
+		"completion zsh",
+		"completion fish",
+		"completion powershell",
+		"completion",
+		"__complete remote dynamicFlags --cluster prod",
+		"__complete remote dynamicFlags -c ",
+		"__complete remote dynamicFlags --cluster=s",
+		"__complete remote dynamicFlags --replicas ",
+		"__complete dynamicFlags --cluster ",
+		"__complete",
+	}
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			runApproved(t, app, test, nil)
+		})
+	}
+}
+
+func manApp() goat.Application {
+	return goat.App("man-app",
+		goat.Command(Documented),
+		goat.Command(copyFile),
+		goat.Command(optionalArgs),
+		goat.Command(envFlags),
+		goat.Command(enumFlags),
+		goat.Command(categorizedFlags),
+		goat.Command(Copy),
+		goat.Command(Database, goat.Command(Migrate)),
+		goat.Command(Legacy),
+		goat.Command(Internal),
+	).ManCommand()
+}
+
+func Test_manPages(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	for _, page := range manApp().ManPages() {
+		stdout.WriteString("==> " + page.Name + " <==\n")
+		stdout.WriteString(page.Content + "\n")
+	}
+	approvals.Verify(t, stdout)
+}
+
+func Test_man(t *testing.T) {
+	app := manApp()
+
+	tests := []string{
+		"man",
+		"man a b",
+		"--help",
+	}
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			runApproved(t, app, test, nil)
+		})
+	}
+}
+
+func Test_writeManPages(t *testing.T) {
+	dir := t.TempDir()
+	if err := manApp().RunWithArgsE([]string{"man-app", "man", dir}); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	approvals.VerifyString(t, strings.Join(names, "\n")+"\n")
+}
+
+func Test_markdownPages(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	for _, page := range manApp().MarkdownPages() {
+		stdout.WriteString("==> " + page.Name + " <==\n")
+		stdout.WriteString(page.Content + "\n")
+	}
+	approvals.Verify(t, stdout)
+}
+
+func Test_writeDocs(t *testing.T) {
+	dir := t.TempDir()
+	if err := manApp().WriteDocs(dir); err != nil {
+		t.Fatal(err)
+	}
+	documented, err := os.ReadFile(dir + "/man-app-Documented.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	approvals.VerifyString(t, string(documented))
+}
+
+func Test_spec(t *testing.T) {
+	app := goat.App("spec-app",
+		goat.Command(Copy),
+		goat.Command(Database, goat.Command(Migrate)),
+		goat.Command(Legacy),
+		goat.Command(Internal),
+		goat.Command(aliasedFlags),
+		goat.Command(envFlags),
+		goat.Command(enumFlags),
+		goat.Command(categorizedFlags),
+		goat.Command(variadicArgs),
+		goat.Group("remote", goat.Command(deprecatedFlags), goat.Command(optionalArgs)).Usage("Remote commands."),
+	).ConfigFile("spec-app.yaml").SpecCommand()
+
+	tests := []string{
+		"spec",
+		"--help",
+	}
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			runApproved(t, app, test, nil)
+		})
+	}
+}
+
+func Test_compareSpecs(t *testing.T) {
+	oldApp := goat.App("tool", goat.Command(serveV1), goat.Command(statusV1), goat.Command(Copy))
+	newApp := goat.App("tool", goat.Command(serveV2), goat.Command(statusV2), goat.Command(NoFlags))
+
+	// The specs are compared after a round trip through JSON, as they are by `goater compat`.
+	readSpec := func(app goat.Application) goat.Spec {
+		buffer := &bytes.Buffer{}
+		if err := app.WriteSpec(buffer); err != nil {
+			t.Fatal(err)
+		}
+		spec, err := goat.ReadSpec(buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return spec
+	}
+	changes := goat.CompareSpecs(readSpec(oldApp), readSpec(newApp))
+
+	stdout := &bytes.Buffer{}
+	for _, change := range changes {
+		stdout.WriteString(change.String() + "\n")
+	}
+	stdout.WriteString(fmt.Sprintf("\n%d breaking changes\n", len(goat.BreakingChanges(changes))))
+	approvals.Verify(t, stdout)
+}
+
+func Test_compareSpecs_unchanged(t *testing.T) {
+	app := goat.App("tool", goat.Command(serveV1), goat.Command(Copy))
+	if changes := goat.CompareSpecs(app.Spec(), app.Spec()); len(changes) != 0 {
+		t.Errorf("CompareSpecs() = %v, want no changes", changes)
+	}
+}
+
+func Test_readSpec_version(t *testing.T) {
+	_, err := goat.ReadSpec(strings.NewReader(`{"specVersion": 2, "name": "tool"}`))
+	approvals.VerifyString(t, err.Error()+"\n")
+}
+
 func Test_subcommands(t *testing.T) {
 	app := goat.App("test-app", goat.Command(noFlags),
 		goat.Command(intFlag),
@@ -111,16 +514,57 @@ func Test_subcommands(t *testing.T) {
 	}
 }
 
+func Test_shortOptions(t *testing.T) {
+	if goat.App("test-app", goat.Command(noFlags), goat.Command(countFlags)).UseShortOptionHandling {
+		t.Error("short option handling is enabled for an app without commands with subcommands")
+	}
+	app := goat.App("test-app", goat.Command(countFlags, goat.Command(noFlags)))
+	if !app.UseShortOptionHandling {
+		t.Error("short option handling is not enabled for an app with a counting command with subcommands")
+	}
+	stdout := &bytes.Buffer{}
+	app.Writer = stdout
+	if err := app.RunWithArgsE([]string{"test-app", "countFlags", "-vvv", "-l"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := stdout.String(); got != "3 3\n" {
+		t.Errorf("countFlags -vvv -l printed %q, want %q", got, "3 3\n")
+	}
+}
+
 func TestApp(t *testing.T) {
 	for _, cmd := range appCmds {
 		args := append([]string{"test-app"}, strings.Split(cmd, " ")...)
 		stdout := &bytes.Buffer{}
 		stdout.WriteString(strings.Join(args, " ") + "\n")
 		stdout.WriteString("-----------------------------------------------------\n\n")
-		app := getApp(stdout, nil)
+		app := getApp(stdout, stdout)
 		t.Run(cmd, func(t *testing.T) {
 			_ = app.RunWithArgsE(args)
 			approvals.Verify(t, stdout)
 		})
 	}
 }
+
+func Test_helpPrinter(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	_ = getApp(stdout, stdout).RunWithArgsE([]string{"test-app", "Database", "--help"})
+
+	// Other cli apps keep the help of cli, even after running a goat app.
+	stdout.Reset()
+	app := &cli.App{
+		Name:   "plain-app",
+		Writer: stdout,
+		Commands: []*cli.Command{{
+			Name:               "parent",
+			CustomHelpTemplate: "custom help\n",
+			Subcommands:        []*cli.Command{{Name: "child"}},
+		}},
+	}
+	if err := app.Run([]string{"plain-app", "parent", "--help"}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(stdout.String(), "custom help") {
+		t.Errorf("help of a plain cli app uses its custom template:\n%s", stdout)
+	}
+}
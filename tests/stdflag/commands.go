@@ -0,0 +1,67 @@
+package stdflag
+
+import (
+	"fmt"
+	goat "github.com/tmr232/goat/stdflag"
+	"time"
+)
+
+//go:generate go run github.com/tmr232/goat/cmd/goater -backend=stdflag
+
+// serve serves files over HTTP.
+//
+// It serves the current directory.
+func serve(ctx *goat.Context, port int, host string, verbose bool, timeout time.Duration, user *string) {
+	goat.Flag(port).Default(8080).Usage("The port to listen on.")
+	goat.Flag(host).Usage("The host to listen on.")
+	goat.Flag(timeout).Name("read-timeout").Default(5 * time.Second)
+
+	userText := "anonymous"
+	if user != nil {
+		userText = *user
+	}
+	fmt.Fprintln(ctx.GetWriter(), host, port, verbose, timeout, userText)
+}
+
+// database works with a database.
+func database(dbHost string, dbPort uint) {
+	goat.Self().Name("db").Category("Admin")
+	goat.Flag(dbHost).Name("db-host").Default("localhost")
+	goat.Flag(dbPort).Name("db-port").Default(5432)
+}
+
+// migrate migrates the database.
+func migrate(ctx *goat.Context, steps *int64) error {
+	dbHost, err := goat.GetFlag[string](ctx, database, "db-host")
+	if err != nil {
+		return err
+	}
+	stepsText := "all"
+	if steps != nil {
+		stepsText = fmt.Sprint(*steps)
+	}
+	fmt.Fprintln(ctx.GetWriter(), "migrating", dbHost, stepsText)
+	return nil
+}
+
+// legacy does things the old way.
+func legacy(ctx *goat.Context, ratio float64) {
+	goat.Self().Deprecated("use serve")
+	goat.Flag(ratio).Default(0.5)
+
+	fmt.Fprintln(ctx.GetWriter(), "legacy", ratio)
+}
+
+// internal is only used for debugging.
+func internal() {
+	goat.Self().Hidden()
+}
+
+func App() goat.Application {
+	return goat.App("std-app",
+		goat.Command(serve),
+		goat.Command(database, goat.Command(migrate)),
+		goat.Command(legacy),
+		goat.Command(internal),
+	).Usage("A tool without urfave/cli.")
+}
@@ -0,0 +1,89 @@
+package stdflag
+
+import (
+	goat "github.com/tmr232/goat/stdflag"
+	"time"
+)
+
+func init() {
+	goat.Register(serve, goat.RunConfig{
+		Flags: []goat.FlagConfig{
+			goat.MakeFlag[int]("port", "The port to listen on.", 8080),
+			goat.MakeFlag[string]("host", "The host to listen on.", nil),
+			goat.MakeFlag[bool]("verbose", "", nil),
+			goat.MakeFlag[time.Duration]("read-timeout", "", 5*time.Second),
+			goat.MakeFlag[*string]("user", "", nil),
+		},
+		Name:  "serve",
+		Usage: "serves files over HTTP.\n\nIt serves the current directory.",
+		Action: func(c *goat.Context) error {
+			serve(
+				c,
+				goat.FlagValue[int](c, "port"),
+				goat.FlagValue[string](c, "host"),
+				goat.FlagValue[bool](c, "verbose"),
+				goat.FlagValue[time.Duration](c, "read-timeout"),
+				goat.FlagValue[*string](c, "user"),
+			)
+			return nil
+		},
+	})
+
+	goat.Register(database, goat.RunConfig{
+		Flags: []goat.FlagConfig{
+			goat.MakeFlag[string]("db-host", "", "localhost"),
+			goat.MakeFlag[uint]("db-port", "", 5432),
+		},
+		Name:     "db",
+		Usage:    "works with a database.",
+		Category: "Admin",
+		Action: func(c *goat.Context) error {
+			database(
+				goat.FlagValue[string](c, "db-host"),
+				goat.FlagValue[uint](c, "db-port"),
+			)
+			return nil
+		},
+	})
+
+	goat.Register(migrate, goat.RunConfig{
+		Flags: []goat.FlagConfig{
+			goat.MakeFlag[*int64]("steps", "", nil),
+		},
+		Name:  "migrate",
+		Usage: "migrates the database.",
+		Action: func(c *goat.Context) error {
+			return migrate(
+				c,
+				goat.FlagValue[*int64](c, "steps"),
+			)
+		},
+	})
+
+	goat.Register(legacy, goat.RunConfig{
+		Flags: []goat.FlagConfig{
+			goat.MakeFlag[float64]("ratio", "", 0.5),
+		},
+		Name:       "legacy",
+		Usage:      "does things the old way.",
+		Deprecated: "use serve",
+		Action: func(c *goat.Context) error {
+			legacy(
+				c,
+				goat.FlagValue[float64](c, "ratio"),
+			)
+			return nil
+		},
+	})
+
+	goat.Register(internal, goat.RunConfig{
+		Flags:  []goat.FlagConfig{},
+		Name:   "internal",
+		Usage:  "is only used for debugging.",
+		Hidden: true,
+		Action: func(c *goat.Context) error {
+			internal()
+			return nil
+		},
+	})
+}
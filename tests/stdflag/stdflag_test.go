@@ -0,0 +1,42 @@
+package stdflag
+
+import (
+	"bytes"
+	"github.com/approvals/go-approval-tests"
+	"strings"
+	"testing"
+)
+
+func Test_stdflag(t *testing.T) {
+	tests := []string{
+		"--help",
+		"serve --help",
+		"serve --host example.com",
+		"serve --host example.com --port 80 -verbose --read-timeout 1m --user admin",
+		"serve --host example.com --port eighty",
+		"serve",
+		"serve extra",
+		"db --help",
+		"db",
+		"db --db-host db.local migrate --steps 3",
+		"db migrate",
+		"db nothing",
+		"legacy --ratio 2",
+		"internal",
+	}
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			args := append([]string{"std-app"}, strings.Split(test, " ")...)
+			app := App()
+			stdout := &bytes.Buffer{}
+			stdout.WriteString(strings.Join(args, " ") + "\n")
+			stdout.WriteString("-----------------------------------------------------\n\n")
+			app.Writer = stdout
+			app.ErrWriter = stdout
+			if err := app.RunWithArgsE(args); err != nil {
+				stdout.WriteString("\n" + err.Error() + "\n")
+			}
+			approvals.Verify(t, stdout)
+		})
+	}
+}
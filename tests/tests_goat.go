@@ -4,6 +4,10 @@ import (
 	"github.com/tmr232/goat"
 	"github.com/tmr232/goat/flags"
 	"github.com/urfave/cli/v2"
+	"math/big"
+	"net"
+	"net/netip"
+	"time"
 )
 
 func init() {
@@ -46,6 +50,119 @@ func init() {
 		},
 	})
 
+	goat.Register(Copy, goat.RunConfig{
+		Flags: []cli.Flag{},
+		Args: []flags.Arg{
+			flags.MakeArg[string]("SRC", "", nil),
+			flags.MakeArg[string]("DST", "", nil),
+		},
+		Name:     "Copy",
+		Usage:    "copies a file.",
+		Category: "Files",
+		Action: func(c *cli.Context) error {
+			if err := flags.CheckArgCount(c, 2, 2); err != nil {
+				return err
+			}
+			arg0, err := flags.GetArg[string](c, 0, "SRC", nil)
+			if err != nil {
+				return err
+			}
+			arg1, err := flags.GetArg[string](c, 1, "DST", nil)
+			if err != nil {
+				return err
+			}
+			Copy(
+				arg0,
+				arg1,
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			return cflags
+		},
+	})
+
+	goat.Register(Database, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[string]("db-host", "The database host.", "localhost", flags.Category("DBOptions")),
+			flags.MakeFlag[int]("db-port", "", 5432, flags.Category("DBOptions")),
+			flags.MakeFlag[*string]("db-user", "An optional user.", nil, flags.Category("DBOptions")),
+			flags.MakeFlag[time.Duration]("db-connect-timeout", "", flags.MustParse[time.Duration]("5s"), flags.Category("DBOptions")),
+		},
+		Name:     "Database",
+		Usage:    "works with a database.",
+		Category: "Admin",
+		Action: func(c *cli.Context) error {
+			Database(
+				DBOptions{
+					Host:    flags.GetFlag[string](c, "db-host"),
+					Port:    flags.GetFlag[int](c, "db-port"),
+					User:    flags.GetFlag[*string](c, "db-user"),
+					Timeout: flags.GetFlag[time.Duration](c, "db-connect-timeout"),
+				},
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["db"] = DBOptions{
+				Host:    flags.GetFlag[string](c, "db-host"),
+				Port:    flags.GetFlag[int](c, "db-port"),
+				User:    flags.GetFlag[*string](c, "db-user"),
+				Timeout: flags.GetFlag[time.Duration](c, "db-connect-timeout"),
+			}
+			return cflags
+		},
+	})
+
+	goat.Register(Migrate, goat.RunConfig{
+		Flags: []cli.Flag{},
+		Name:  "Migrate",
+		Usage: "migrates the database.",
+		Action: func(c *cli.Context) error {
+			return Migrate(
+				goat.GetContext(c),
+			)
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			return cflags
+		},
+	})
+
+	goat.Register(Legacy, goat.RunConfig{
+		Flags:      []cli.Flag{},
+		Name:       "Legacy",
+		Usage:      "does things the old way.",
+		Deprecated: "use NoFlags",
+		Action: func(c *cli.Context) error {
+			Legacy(
+				goat.GetContext(c),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			return cflags
+		},
+	})
+
+	goat.Register(Internal, goat.RunConfig{
+		Flags:  []cli.Flag{},
+		Name:   "Internal",
+		Usage:  "is only used for debugging.",
+		Hidden: true,
+		Action: func(c *cli.Context) error {
+			Internal()
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			return cflags
+		},
+	})
+
 	goat.Register(noFlags, goat.RunConfig{
 		Flags: []cli.Flag{},
 		Name:  "noFlags",
@@ -172,4 +289,890 @@ func init() {
 			return cflags
 		},
 	})
+
+	goat.Register(floatFlags, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[float64]("ratio", "A ratio.", nil),
+			flags.MakeFlag[*float32]("scale", "An optional scale.", nil),
+		},
+		Name:  "floatFlags",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			floatFlags(
+				flags.GetFlag[float64](c, "ratio"),
+				flags.GetFlag[*float32](c, "scale"),
+				goat.GetContext(c),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["ratio"] = flags.GetFlag[float64](c, "ratio")
+			cflags["scale"] = flags.GetFlag[*float32](c, "scale")
+			return cflags
+		},
+	})
+
+	goat.Register(defaultFloat, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[float64]("ratio", "", 1),
+			flags.MakeFlag[float32]("scale", "", 0.5),
+		},
+		Name:  "defaultFloat",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			defaultFloat(
+				flags.GetFlag[float64](c, "ratio"),
+				flags.GetFlag[float32](c, "scale"),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["ratio"] = flags.GetFlag[float64](c, "ratio")
+			cflags["scale"] = flags.GetFlag[float32](c, "scale")
+			return cflags
+		},
+	})
+
+	goat.Register(sizedInts, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[uint16]("port", "A port number.", nil),
+			flags.MakeFlag[int8]("offset", "", -1),
+			flags.MakeFlag[uint64]("size", "", 1<<40),
+			flags.MakeFlag[*int64]("id", "", nil),
+		},
+		Name:  "sizedInts",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			sizedInts(
+				flags.GetFlag[uint16](c, "port"),
+				flags.GetFlag[int8](c, "offset"),
+				flags.GetFlag[uint64](c, "size"),
+				flags.GetFlag[*int64](c, "id"),
+				goat.GetContext(c),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["port"] = flags.GetFlag[uint16](c, "port")
+			cflags["offset"] = flags.GetFlag[int8](c, "offset")
+			cflags["size"] = flags.GetFlag[uint64](c, "size")
+			cflags["id"] = flags.GetFlag[*int64](c, "id")
+			return cflags
+		},
+	})
+
+	goat.Register(timeFlags, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[time.Duration]("timeout", "", 5*time.Second),
+			flags.MakeFlag[*time.Time]("since", "", nil),
+			flags.MakeFlag[time.Time]("until", "The last day.", nil, flags.Layout("2006-01-02")),
+		},
+		Name:  "timeFlags",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			timeFlags(
+				flags.GetFlag[time.Duration](c, "timeout"),
+				flags.GetFlag[*time.Time](c, "since"),
+				flags.GetFlag[time.Time](c, "until"),
+				goat.GetContext(c),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["timeout"] = flags.GetFlag[time.Duration](c, "timeout")
+			cflags["since"] = flags.GetFlag[*time.Time](c, "since")
+			cflags["until"] = flags.GetFlag[time.Time](c, "until")
+			return cflags
+		},
+	})
+
+	goat.Register(sliceFlags, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[[]string]("tag", "", []string{"x"}),
+			flags.MakeFlag[[]int]("id", "", nil, flags.Separator(",")),
+			flags.MakeFlag[[]float64]("weight", "", nil),
+		},
+		Name:  "sliceFlags",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			sliceFlags(
+				flags.GetFlag[[]string](c, "tag"),
+				flags.GetFlag[[]int](c, "id"),
+				flags.GetFlag[[]float64](c, "weight"),
+				goat.GetContext(c),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["tag"] = flags.GetFlag[[]string](c, "tag")
+			cflags["id"] = flags.GetFlag[[]int](c, "id")
+			cflags["weight"] = flags.GetFlag[[]float64](c, "weight")
+			return cflags
+		},
+	})
+
+	goat.Register(mapFlags, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[map[string]string]("label", "Labels to apply.", nil),
+			flags.MakeFlag[map[string]int]("limit", "", map[string]int{"cpu": 1}, flags.Separator(",")),
+		},
+		Name:  "mapFlags",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			mapFlags(
+				flags.GetFlag[map[string]string](c, "label"),
+				flags.GetFlag[map[string]int](c, "limit"),
+				goat.GetContext(c),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["label"] = flags.GetFlag[map[string]string](c, "label")
+			cflags["limit"] = flags.GetFlag[map[string]int](c, "limit")
+			return cflags
+		},
+	})
+
+	goat.Register(enumFlags, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[Format]("format", "Output format.", nil, flags.Choice("json", JSON), flags.Choice("yaml", YAML)),
+			flags.MakeFlag[*Color]("color", "An optional color.", nil, flags.Choice("Red", Red), flags.Choice("Green", Green), flags.Choice("Blue", Blue)),
+		},
+		Name:  "enumFlags",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			enumFlags(
+				flags.GetFlag[Format](c, "format"),
+				flags.GetFlag[*Color](c, "color"),
+				goat.GetContext(c),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["format"] = flags.GetFlag[Format](c, "format")
+			cflags["color"] = flags.GetFlag[*Color](c, "color")
+			return cflags
+		},
+	})
+
+	goat.Register(defaultEnum, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[Format]("format", "", YAML, flags.Choice("json", JSON), flags.Choice("yaml", YAML)),
+		},
+		Name:  "defaultEnum",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			defaultEnum(
+				flags.GetFlag[Format](c, "format"),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["format"] = flags.GetFlag[Format](c, "format")
+			return cflags
+		},
+	})
+
+	goat.Register(textFlags, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[net.IP]("ip", "An IP address.", nil),
+			flags.MakeFlag[*netip.Addr]("addr", "", nil),
+			flags.MakeFlag[*big.Int]("count", "", nil),
+			flags.MakeFlag[Level]("level", "", Level{"info"}),
+		},
+		Name:  "textFlags",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			textFlags(
+				flags.GetFlag[net.IP](c, "ip"),
+				flags.GetFlag[*netip.Addr](c, "addr"),
+				flags.GetFlag[*big.Int](c, "count"),
+				flags.GetFlag[Level](c, "level"),
+				goat.GetContext(c),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["ip"] = flags.GetFlag[net.IP](c, "ip")
+			cflags["addr"] = flags.GetFlag[*netip.Addr](c, "addr")
+			cflags["count"] = flags.GetFlag[*big.Int](c, "count")
+			cflags["level"] = flags.GetFlag[Level](c, "level")
+			return cflags
+		},
+	})
+
+	goat.Register(textDefaults, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[time.Time]("since", "", "2024-01-01", flags.Layout("2006-01-02")),
+			flags.MakeFlag[netip.Addr]("addr", "", "127.0.0.1"),
+			flags.MakeFlag[Level]("level", "", "warn"),
+		},
+		Name:  "textDefaults",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			textDefaults(
+				flags.GetFlag[time.Time](c, "since"),
+				flags.GetFlag[netip.Addr](c, "addr"),
+				flags.GetFlag[Level](c, "level"),
+				goat.GetContext(c),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["since"] = flags.GetFlag[time.Time](c, "since")
+			cflags["addr"] = flags.GetFlag[netip.Addr](c, "addr")
+			cflags["level"] = flags.GetFlag[Level](c, "level")
+			return cflags
+		},
+	})
+
+	goat.Register(registeredType, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[Celsius]("temperature", "The temperature, in Celsius.", Celsius(20)),
+		},
+		Name:  "registeredType",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			registeredType(
+				flags.GetFlag[Celsius](c, "temperature"),
+				goat.GetContext(c),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["temperature"] = flags.GetFlag[Celsius](c, "temperature")
+			return cflags
+		},
+	})
+
+	goat.Register(copyFile, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[bool]("force", "Overwrite an existing file.", nil),
+		},
+		Args: []flags.Arg{
+			flags.MakeArg[string]("SRC", "The file to copy.", nil),
+			flags.MakeArg[string]("DST", "Where to copy it.", nil),
+		},
+		Name:  "copyFile",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			if err := flags.CheckArgCount(c, 2, 2); err != nil {
+				return err
+			}
+			arg0, err := flags.GetArg[string](c, 0, "SRC", nil)
+			if err != nil {
+				return err
+			}
+			arg1, err := flags.GetArg[string](c, 1, "DST", nil)
+			if err != nil {
+				return err
+			}
+			copyFile(
+				arg0,
+				arg1,
+				flags.GetFlag[bool](c, "force"),
+				goat.GetContext(c),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["force"] = flags.GetFlag[bool](c, "force")
+			return cflags
+		},
+	})
+
+	goat.Register(optionalArgs, goat.RunConfig{
+		Flags: []cli.Flag{},
+		Args: []flags.Arg{
+			flags.MakeArg[int]("COUNT", "", nil),
+			flags.MakeArg[string]("UNIT", "", "bytes"),
+			flags.MakeArg[*Format]("FORMAT", "", nil, flags.Choice("json", JSON), flags.Choice("yaml", YAML)),
+		},
+		Name:  "optionalArgs",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			if err := flags.CheckArgCount(c, 1, 3); err != nil {
+				return err
+			}
+			arg0, err := flags.GetArg[int](c, 0, "COUNT", nil)
+			if err != nil {
+				return err
+			}
+			arg1, err := flags.GetArg[string](c, 1, "UNIT", "bytes")
+			if err != nil {
+				return err
+			}
+			arg2, err := flags.GetArg[*Format](c, 2, "FORMAT", nil, flags.Choice("json", JSON), flags.Choice("yaml", YAML))
+			if err != nil {
+				return err
+			}
+			optionalArgs(
+				arg0,
+				arg1,
+				arg2,
+				goat.GetContext(c),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			return cflags
+		},
+	})
+
+	goat.Register(variadicArgs, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[bool]("verbose", "", nil),
+		},
+		Args: []flags.Arg{
+			flags.MakeVariadicArg[string]("FILES", ""),
+		},
+		Name:  "variadicArgs",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			if err := flags.CheckArgCount(c, 0, -1); err != nil {
+				return err
+			}
+			arg0, err := flags.GetVariadicArg[string](c, 0, "FILES")
+			if err != nil {
+				return err
+			}
+			variadicArgs(
+				goat.GetContext(c),
+				flags.GetFlag[bool](c, "verbose"),
+				arg0...,
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["verbose"] = flags.GetFlag[bool](c, "verbose")
+			return cflags
+		},
+	})
+
+	goat.Register(variadicInts, goat.RunConfig{
+		Flags: []cli.Flag{},
+		Args: []flags.Arg{
+			flags.MakeArg[int]("BASE", "The number to start from.", nil),
+			flags.MakeVariadicArg[int]("N", "Numbers to add."),
+		},
+		Name:  "variadicInts",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			if err := flags.CheckArgCount(c, 1, -1); err != nil {
+				return err
+			}
+			arg0, err := flags.GetArg[int](c, 0, "BASE", nil)
+			if err != nil {
+				return err
+			}
+			arg1, err := flags.GetVariadicArg[int](c, 1, "N")
+			if err != nil {
+				return err
+			}
+			variadicInts(
+				goat.GetContext(c),
+				arg0,
+				arg1...,
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			return cflags
+		},
+	})
+
+	goat.Register(deploy, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[string]("db-host", "The database host.", "localhost", flags.Category("DBOptions")),
+			flags.MakeFlag[int]("db-port", "", 5432, flags.Category("DBOptions")),
+			flags.MakeFlag[*string]("db-user", "An optional user.", nil, flags.Category("DBOptions")),
+			flags.MakeFlag[time.Duration]("db-connect-timeout", "", flags.MustParse[time.Duration]("5s"), flags.Category("DBOptions")),
+			flags.MakeFlag[uint16]("http-port", "Port to listen on, on all interfaces.", 8080, flags.Category("HTTPOptions")),
+			flags.MakeFlag[bool]("http-tls", "", nil, flags.Category("HTTPOptions")),
+			flags.MakeFlag[Format]("http-log-format", "", flags.MustParse[Format]("json", flags.Choice("json", JSON), flags.Choice("yaml", YAML)), flags.Choice("json", JSON), flags.Choice("yaml", YAML), flags.Category("HTTPOptions")),
+			flags.MakeFlag[bool]("dryRun", "Only print what would be deployed.", nil),
+		},
+		Name:  "deploy",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			deploy(
+				DBOptions{
+					Host:    flags.GetFlag[string](c, "db-host"),
+					Port:    flags.GetFlag[int](c, "db-port"),
+					User:    flags.GetFlag[*string](c, "db-user"),
+					Timeout: flags.GetFlag[time.Duration](c, "db-connect-timeout"),
+				},
+				HTTPOptions{
+					Port:      flags.GetFlag[uint16](c, "http-port"),
+					TLS:       flags.GetFlag[bool](c, "http-tls"),
+					LogFormat: flags.GetFlag[Format](c, "http-log-format"),
+				},
+				flags.GetFlag[bool](c, "dryRun"),
+				goat.GetContext(c),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["db"] = DBOptions{
+				Host:    flags.GetFlag[string](c, "db-host"),
+				Port:    flags.GetFlag[int](c, "db-port"),
+				User:    flags.GetFlag[*string](c, "db-user"),
+				Timeout: flags.GetFlag[time.Duration](c, "db-connect-timeout"),
+			}
+			cflags["http"] = HTTPOptions{
+				Port:      flags.GetFlag[uint16](c, "http-port"),
+				TLS:       flags.GetFlag[bool](c, "http-tls"),
+				LogFormat: flags.GetFlag[Format](c, "http-log-format"),
+			}
+			cflags["dryRun"] = flags.GetFlag[bool](c, "dryRun")
+			return cflags
+		},
+	})
+
+	goat.Register(countFlags, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[int]("verbose", "Increase verbosity.", nil, flags.Count(), flags.Alias("v")),
+			flags.MakeFlag[int]("level", "", 2, flags.Count(), flags.Alias("l")),
+		},
+		Name:  "countFlags",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			countFlags(
+				goat.GetContext(c),
+				flags.GetFlag[int](c, "verbose"),
+				flags.GetFlag[int](c, "level"),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["verbose"] = flags.GetFlag[int](c, "verbose")
+			cflags["level"] = flags.GetFlag[int](c, "level")
+			return cflags
+		},
+	})
+
+	goat.Register(aliasedFlags, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[int]("count", "", 1, flags.Alias("n", "number")),
+			flags.MakeFlag[bool]("verbose", "", nil, flags.Alias("v")),
+		},
+		Name:  "aliasedFlags",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			aliasedFlags(
+				goat.GetContext(c),
+				flags.GetFlag[int](c, "count"),
+				flags.GetFlag[bool](c, "verbose"),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["count"] = flags.GetFlag[int](c, "count")
+			cflags["verbose"] = flags.GetFlag[bool](c, "verbose")
+			return cflags
+		},
+	})
+
+	goat.Register(envFlags, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[string]("token", "The API token.", nil, flags.EnvVar("APP_TOKEN", "TOKEN")),
+			flags.MakeFlag[string]("region", "", "us", flags.EnvVar("APP_REGION")),
+			flags.MakeFlag[*int]("retries", "", nil),
+		},
+		Name:  "envFlags",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			envFlags(
+				goat.GetContext(c),
+				flags.GetFlag[string](c, "token"),
+				flags.GetFlag[string](c, "region"),
+				flags.GetFlag[*int](c, "retries"),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["token"] = flags.GetFlag[string](c, "token")
+			cflags["region"] = flags.GetFlag[string](c, "region")
+			cflags["retries"] = flags.GetFlag[*int](c, "retries")
+			return cflags
+		},
+	})
+
+	goat.Register(envPrefix, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[string]("name", "", nil, flags.EnvVar("NAME")),
+			flags.MakeFlag[string]("db-host", "The database host.", "localhost", flags.Category("DBOptions")),
+			flags.MakeFlag[int]("db-port", "", 5432, flags.Category("DBOptions")),
+			flags.MakeFlag[*string]("db-user", "An optional user.", nil, flags.Category("DBOptions")),
+			flags.MakeFlag[time.Duration]("db-connect-timeout", "", flags.MustParse[time.Duration]("5s"), flags.Category("DBOptions")),
+		},
+		Name:  "envPrefix",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			envPrefix(
+				goat.GetContext(c),
+				flags.GetFlag[string](c, "name"),
+				DBOptions{
+					Host:    flags.GetFlag[string](c, "db-host"),
+					Port:    flags.GetFlag[int](c, "db-port"),
+					User:    flags.GetFlag[*string](c, "db-user"),
+					Timeout: flags.GetFlag[time.Duration](c, "db-connect-timeout"),
+				},
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["name"] = flags.GetFlag[string](c, "name")
+			cflags["db"] = DBOptions{
+				Host:    flags.GetFlag[string](c, "db-host"),
+				Port:    flags.GetFlag[int](c, "db-port"),
+				User:    flags.GetFlag[*string](c, "db-user"),
+				Timeout: flags.GetFlag[time.Duration](c, "db-connect-timeout"),
+			}
+			return cflags
+		},
+	})
+
+	goat.Register(deprecatedFlags, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[string]("name", "", "goat"),
+			flags.MakeFlag[*string]("old-name", "The name to use.", nil, flags.Deprecated("use --name")),
+			flags.MakeFlag[bool]("debug", "", nil, flags.Hidden()),
+		},
+		Name:  "deprecatedFlags",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			deprecatedFlags(
+				goat.GetContext(c),
+				flags.GetFlag[string](c, "name"),
+				flags.GetFlag[*string](c, "old-name"),
+				flags.GetFlag[bool](c, "debug"),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["name"] = flags.GetFlag[string](c, "name")
+			cflags["old-name"] = flags.GetFlag[*string](c, "old-name")
+			cflags["debug"] = flags.GetFlag[bool](c, "debug")
+			return cflags
+		},
+	})
+
+	goat.Register(flagConstraints, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[bool]("json", "", nil),
+			flags.MakeFlag[bool]("yaml", "", nil),
+			flags.MakeFlag[*string]("user", "", nil),
+			flags.MakeFlag[*string]("password", "", nil),
+		},
+		Name:  "flagConstraints",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			if err := flags.CheckExclusive(c, "json", "yaml"); err != nil {
+				return err
+			}
+			if err := flags.CheckTogether(c, "user", "password"); err != nil {
+				return err
+			}
+			flagConstraints(
+				goat.GetContext(c),
+				flags.GetFlag[bool](c, "json"),
+				flags.GetFlag[bool](c, "yaml"),
+				flags.GetFlag[*string](c, "user"),
+				flags.GetFlag[*string](c, "password"),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["json"] = flags.GetFlag[bool](c, "json")
+			cflags["yaml"] = flags.GetFlag[bool](c, "yaml")
+			cflags["user"] = flags.GetFlag[*string](c, "user")
+			cflags["password"] = flags.GetFlag[*string](c, "password")
+			return cflags
+		},
+	})
+
+	goat.Register(validatedFlags, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[int]("port", "Port to listen on.", 8080, flags.Range[int](1, 65535)),
+			flags.MakeFlag[string]("name", "", nil, flags.MinLen(3), flags.Pattern("^[a-z]+$")),
+			flags.MakeFlag[string]("level", "", "info", flags.OneOf[string]("debug", "info")),
+			flags.MakeFlag[*string]("user", "", nil, flags.Validate(checkNotRoot)),
+			flags.MakeFlag[[]string]("tags", "", nil, flags.MinLen(1)),
+		},
+		Name:  "validatedFlags",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			if err := flags.ValidateFlags(c,
+				flags.ValidateFlag[int](c, "port", flags.Range[int](1, 65535)),
+				flags.ValidateFlag[string](c, "name", flags.MinLen(3), flags.Pattern("^[a-z]+$")),
+				flags.ValidateFlag[string](c, "level", flags.OneOf[string]("debug", "info")),
+				flags.ValidateFlag[*string](c, "user", flags.Validate(checkNotRoot)),
+				flags.ValidateFlag[[]string](c, "tags", flags.MinLen(1)),
+			); err != nil {
+				return err
+			}
+			validatedFlags(
+				goat.GetContext(c),
+				flags.GetFlag[int](c, "port"),
+				flags.GetFlag[string](c, "name"),
+				flags.GetFlag[string](c, "level"),
+				flags.GetFlag[*string](c, "user"),
+				flags.GetFlag[[]string](c, "tags"),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["port"] = flags.GetFlag[int](c, "port")
+			cflags["name"] = flags.GetFlag[string](c, "name")
+			cflags["level"] = flags.GetFlag[string](c, "level")
+			cflags["user"] = flags.GetFlag[*string](c, "user")
+			cflags["tags"] = flags.GetFlag[[]string](c, "tags")
+			return cflags
+		},
+	})
+
+	goat.Register(categorizedFlags, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[string]("host", "", "localhost", flags.Category("Networking")),
+			flags.MakeFlag[int]("port", "", 80, flags.Category("Networking")),
+			flags.MakeFlag[bool]("verbose", "", nil),
+			flags.MakeFlag[int]("retries", "", 3, flags.Category("Behaviour")),
+		},
+		Name:  "categorizedFlags",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			categorizedFlags(
+				goat.GetContext(c),
+				flags.GetFlag[string](c, "host"),
+				flags.GetFlag[int](c, "port"),
+				flags.GetFlag[bool](c, "verbose"),
+				flags.GetFlag[int](c, "retries"),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["host"] = flags.GetFlag[string](c, "host")
+			cflags["port"] = flags.GetFlag[int](c, "port")
+			cflags["verbose"] = flags.GetFlag[bool](c, "verbose")
+			cflags["retries"] = flags.GetFlag[int](c, "retries")
+			return cflags
+		},
+	})
+
+	goat.Register(fileFlags, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[string]("input", "The file to read.", nil, flags.File()),
+			flags.MakeFlag[*string]("output", "", nil, flags.File(), flags.Alias("o")),
+			flags.MakeFlag[Format]("format", "", nil, flags.Choice("json", JSON), flags.Choice("yaml", YAML)),
+		},
+		Name:  "fileFlags",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			fileFlags(
+				goat.GetContext(c),
+				flags.GetFlag[string](c, "input"),
+				flags.GetFlag[*string](c, "output"),
+				flags.GetFlag[Format](c, "format"),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["input"] = flags.GetFlag[string](c, "input")
+			cflags["output"] = flags.GetFlag[*string](c, "output")
+			cflags["format"] = flags.GetFlag[Format](c, "format")
+			return cflags
+		},
+	})
+
+	goat.Register(dynamicFlags, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[string]("cluster", "", nil, flags.Complete(goat.CompleteFunc(clusterNames)), flags.Alias("c")),
+			flags.MakeFlag[int]("replicas", "", nil),
+		},
+		Name:  "dynamicFlags",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			dynamicFlags(
+				goat.GetContext(c),
+				flags.GetFlag[string](c, "cluster"),
+				flags.GetFlag[int](c, "replicas"),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["cluster"] = flags.GetFlag[string](c, "cluster")
+			cflags["replicas"] = flags.GetFlag[int](c, "replicas")
+			return cflags
+		},
+	})
+
+	goat.Register(serveV1, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[int]("port", "", 8080, flags.EnvVar("PORT")),
+			flags.MakeFlag[string]("host", "", "localhost"),
+			flags.MakeFlag[bool]("verbose", "", nil, flags.Alias("v")),
+			flags.MakeFlag[*Format]("format", "", nil, flags.Choice("json", JSON), flags.Choice("yaml", YAML)),
+			flags.MakeFlag[*Color]("color", "", nil, flags.Choice("Red", Red), flags.Choice("Green", Green), flags.Choice("Blue", Blue)),
+			flags.MakeFlag[int]("workers", "", 4),
+		},
+		Args: []flags.Arg{
+			flags.MakeArg[string]("SRC", "", nil),
+			flags.MakeVariadicArg[string]("EXTRA", ""),
+		},
+		Name:  "serve",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			if err := flags.CheckArgCount(c, 1, -1); err != nil {
+				return err
+			}
+			arg0, err := flags.GetArg[string](c, 0, "SRC", nil)
+			if err != nil {
+				return err
+			}
+			arg1, err := flags.GetVariadicArg[string](c, 1, "EXTRA")
+			if err != nil {
+				return err
+			}
+			serveV1(
+				goat.GetContext(c),
+				flags.GetFlag[int](c, "port"),
+				flags.GetFlag[string](c, "host"),
+				flags.GetFlag[bool](c, "verbose"),
+				flags.GetFlag[*Format](c, "format"),
+				flags.GetFlag[*Color](c, "color"),
+				flags.GetFlag[int](c, "workers"),
+				arg0,
+				arg1...,
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["port"] = flags.GetFlag[int](c, "port")
+			cflags["host"] = flags.GetFlag[string](c, "host")
+			cflags["verbose"] = flags.GetFlag[bool](c, "verbose")
+			cflags["format"] = flags.GetFlag[*Format](c, "format")
+			cflags["color"] = flags.GetFlag[*Color](c, "color")
+			cflags["workers"] = flags.GetFlag[int](c, "workers")
+			return cflags
+		},
+	})
+
+	goat.Register(serveV2, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[string]("port", "", "8080"),
+			flags.MakeFlag[string]("address", "", "localhost", flags.Alias("host")),
+			flags.MakeFlag[bool]("verbose", "", nil),
+			flags.MakeFlag[Format]("format", "", nil, flags.Choice("json", JSON), flags.Choice("yaml", YAML)),
+			flags.MakeFlag[*Color]("color", "", nil, flags.Choice("Red", Red), flags.Choice("Green", Green), flags.Choice("Blue", Blue)),
+			flags.MakeFlag[int]("workers", "", 8),
+			flags.MakeFlag[bool]("debug", "", nil, flags.Deprecated("use --verbose")),
+		},
+		Args: []flags.Arg{
+			flags.MakeArg[string]("SRC", "", nil),
+			flags.MakeArg[string]("DST", "", nil),
+		},
+		Name:  "serve",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			if err := flags.CheckArgCount(c, 2, 2); err != nil {
+				return err
+			}
+			arg0, err := flags.GetArg[string](c, 0, "SRC", nil)
+			if err != nil {
+				return err
+			}
+			arg1, err := flags.GetArg[string](c, 1, "DST", nil)
+			if err != nil {
+				return err
+			}
+			serveV2(
+				goat.GetContext(c),
+				flags.GetFlag[string](c, "port"),
+				flags.GetFlag[string](c, "address"),
+				flags.GetFlag[bool](c, "verbose"),
+				flags.GetFlag[Format](c, "format"),
+				flags.GetFlag[*Color](c, "color"),
+				flags.GetFlag[int](c, "workers"),
+				flags.GetFlag[bool](c, "debug"),
+				arg0,
+				arg1,
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["port"] = flags.GetFlag[string](c, "port")
+			cflags["address"] = flags.GetFlag[string](c, "address")
+			cflags["verbose"] = flags.GetFlag[bool](c, "verbose")
+			cflags["format"] = flags.GetFlag[Format](c, "format")
+			cflags["color"] = flags.GetFlag[*Color](c, "color")
+			cflags["workers"] = flags.GetFlag[int](c, "workers")
+			cflags["debug"] = flags.GetFlag[bool](c, "debug")
+			return cflags
+		},
+	})
+
+	goat.Register(statusV1, goat.RunConfig{
+		Flags: []cli.Flag{},
+		Name:  "status",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			statusV1(
+				goat.GetContext(c),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			return cflags
+		},
+	})
+
+	goat.Register(statusV2, goat.RunConfig{
+		Flags: []cli.Flag{
+			flags.MakeFlag[string]("token", "", nil),
+		},
+		Name:  "status",
+		Usage: "",
+		Action: func(c *cli.Context) error {
+			statusV2(
+				goat.GetContext(c),
+				flags.GetFlag[string](c, "token"),
+			)
+			return nil
+		},
+		CtxFlagBuilder: func(c *cli.Context) map[string]any {
+			cflags := make(map[string]any)
+			cflags["token"] = flags.GetFlag[string](c, "token")
+			return cflags
+		},
+	})
 }